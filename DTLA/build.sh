@@ -5,6 +5,17 @@ bold_green="\033[1;32m"
 normal="\033[0m"
 
 mkdir -p build
+
+# Compressed copies of the public files get embedded too
+printf "Precompressing public... "
+go run ./cmd/dtla precompress -dir public > build/precompress.log 2>&1
+if [ $? -eq 0 ]; then
+	printf "${bold_green}OK${normal}\n"
+else
+	# Building would embed missing or stale copies
+	printf "${bold_red}ERROR${normal} (build/precompress.log)\n"
+	exit 1
+fi
 declare -a oss=("windows" "linux" "darwin")
 declare -a archs=("amd64" "arm64")
 declare -a cmds=("dtla" "crypto")
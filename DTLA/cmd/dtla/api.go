@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql"
+	"dtla/internal/apperr"
+	"dtla/internal/i18n"
+	"dtla/internal/post"
+	"dtla/internal/util"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// Handlers for /api/v1/, documented in public/api/v1/openapi.json
+
+func apiPostsHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	pages, err := hd.sstate.Posts.All(r.Context())
+	if err != nil {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusInternalServerError, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, pages)
+}
+
+func apiPostHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	id, ok := apiPostID(w, r, hd.tmpl.Lang)
+	if !ok {
+		return
+	}
+
+	page, err := hd.sstate.Posts.Get(r.Context(), id)
+	if err != nil {
+		apiStoreError(w, hd.tmpl.Lang, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, page)
+}
+
+func apiPostCreateHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if !apiCanWrite(w, r, hd) {
+		return
+	}
+
+	var page post.Page
+	err = page.LoadJSON(r.Body)
+	if err == nil {
+		err = checkShortcodes(page.Body, hd.sstate.Public)
+	}
+	if err != nil {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusBadRequest, err)
+		return
+	}
+
+	err = hd.sstate.Posts.Insert(r.Context(), &page)
+	if err != nil {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/posts/"+strconv.Itoa(page.ID))
+	util.WriteJSON(w, http.StatusCreated, &page)
+}
+
+func apiPostUpdateHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if !apiCanWrite(w, r, hd) {
+		return
+	}
+
+	id, ok := apiPostID(w, r, hd.tmpl.Lang)
+	if !ok {
+		return
+	}
+
+	page, err := hd.sstate.Posts.Get(r.Context(), id)
+	if err != nil {
+		apiStoreError(w, hd.tmpl.Lang, err)
+		return
+	}
+
+	err = page.LoadJSON(r.Body)
+	if err == nil {
+		err = checkShortcodes(page.Body, hd.sstate.Public)
+	}
+	if err != nil {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusBadRequest, err)
+		return
+	}
+
+	err = hd.sstate.Posts.Save(r.Context(), page)
+	if err != nil {
+		apiStoreError(w, hd.tmpl.Lang, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, page)
+}
+
+func apiPostDeleteHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusUnauthorized, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.api.unauthorized")))
+		return
+	}
+
+	id, ok := apiPostID(w, r, hd.tmpl.Lang)
+	if !ok {
+		return
+	}
+
+	err = hd.sstate.Posts.Trash(r.Context(), id, hd.tmpl.Auth.User)
+	if err != nil {
+		apiStoreError(w, hd.tmpl.Lang, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Requests that change posts need a logged in user and a JSON body.
+// Requiring application/json also means a cross-site form can't make the request.
+func apiCanWrite(w http.ResponseWriter, r *http.Request, hd *handlerData) bool {
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusUnauthorized, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.api.unauthorized")))
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusUnsupportedMediaType, apperr.New(apperr.BadRequest, i18n.T(hd.tmpl.Lang, "err.api.content-type")))
+		return false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, post.MaxBodyLen+64<<10)
+	return true
+}
+
+func apiPostID(w http.ResponseWriter, r *http.Request, lang string) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		util.WriteJSONError(w, lang, http.StatusBadRequest, err)
+		return 0, false
+	}
+	return id, true
+}
+
+func apiStoreError(w http.ResponseWriter, lang string, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		util.WriteJSONError(w, lang, http.StatusNotFound, apperr.New(apperr.NotFound, i18n.T(lang, "err.post.not-found")))
+		return
+	}
+	if errors.Is(err, post.ErrConflict) {
+		util.WriteJSONError(w, lang, http.StatusConflict, err)
+		return
+	}
+	util.WriteJSONError(w, lang, http.StatusInternalServerError, err)
+}
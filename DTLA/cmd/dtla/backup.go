@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"dtla/internal/backup"
+	"dtla/internal/database"
+	"dtla/internal/i18n"
+	"dtla/internal/migrate"
+	"dtla/internal/util"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func backupCmd(args []string) error {
+	var err error
+
+	fset := flag.NewFlagSet("backup", flag.ExitOnError)
+	dbName := fset.String("db", "db", i18n.T(cliLang, "flag.db"))
+	outDir := fset.String("out", "backups", i18n.T(cliLang, "flag.backup.out"))
+	keep := fset.Int("keep", 7, i18n.T(cliLang, "flag.backup-keep"))
+	fset.Parse(args)
+
+	// Opening would create a missing file, and a backup is made before
+	// upgrading, so the database is neither created nor migrated
+	_, err = os.Stat(*dbName)
+	if err != nil {
+		return err
+	}
+	db, err := database.Open(*dbName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Not some other file given by mistake
+	err = migrate.Verify(db)
+	if err != nil {
+		return err
+	}
+
+	path, err := backup.Create(context.Background(), db, *outDir, *keep)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Rezerves kopija saglabāta '%s'\n", path)
+	return nil
+}
+
+// dtla restore [opcijas] <fails>
+func restoreCmd(args []string) error {
+	var err error
+
+	fset := flag.NewFlagSet("restore", flag.ExitOnError)
+	dbName := fset.String("db", "db", i18n.T(cliLang, "flag.restore.db"))
+	skipChecksum := fset.Bool("skip-checksum", false, i18n.T(cliLang, "flag.restore.skip-checksum"))
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), i18n.T(cliLang, "usage.restore", filepath.Base(os.Args[0])))
+		fset.PrintDefaults()
+	}
+	fset.Parse(args)
+
+	if fset.NArg() != 1 {
+		fset.Usage()
+		os.Exit(2)
+	}
+	path := fset.Arg(0)
+
+	err = backup.VerifyChecksum(path)
+	if errors.Is(err, backup.ErrNoChecksum) && *skipChecksum {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = backup.Restore(path, *dbName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Datubāze '%s' atjaunota no '%s'\n", *dbName, path)
+	return nil
+}
+
+// Periodically back up the database into -backup-dir while the server runs
+func BackupDB(sstate *ServerState) {
+	if *sstate.BackupDir == "" || *sstate.BackupEvery <= 0 {
+		return
+	}
+	if *sstate.Mem {
+		util.LogError("Ar -mem rezerves kopijas netiek veidotas")
+		return
+	}
+
+	ticker := time.NewTicker(*sstate.BackupEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-sstate.ctx.Done():
+			return
+		}
+
+		path, err := backup.Create(sstate.ctx, sstate.DB, *sstate.BackupDir, *sstate.BackupKeep)
+		if err != nil {
+			util.LogError(err.Error())
+		} else if *sstate.Verbose {
+			util.LogInfof("Rezerves kopija saglabāta '%s'\n", path)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"dtla/internal/i18n"
+	"dtla/internal/post"
+	"dtla/internal/util"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Render the site into a directory of static files which can be served by any web server.
+// Pages are written as .html files and links to them are rewritten to match.
+func buildCmd(args []string) error {
+	var err error
+
+	fset := flag.NewFlagSet("build", flag.ExitOnError)
+	dbName := fset.String("db", "db", i18n.T(cliLang, "flag.db"))
+	publicDir := fset.String("public", "public", i18n.T(cliLang, "flag.build.public"))
+	tmplDir := fset.String("tmpl", "public/tmpl", i18n.T(cliLang, "flag.build.tmpl"))
+	outDir := fset.String("out", "build/site", i18n.T(cliLang, "flag.build.out"))
+	fset.Parse(args)
+
+	db, err := openExistingDB(*dbName, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	public, partials := publicFS(*publicDir, *tmplDir)
+
+	err = copyAssets(public, *publicDir, *outDir)
+	if err != nil {
+		return err
+	}
+
+	tmpls, err := util.NewTemplates(public, partials, textPages, i18n.Funcs())
+	if err != nil {
+		return err
+	}
+
+	pages, err := post.GetAllPages(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	err = buildPage(*outDir, "index.html", "index.html", "/", tmpls, nil, util.ExecuteTemplate)
+	if err != nil {
+		return err
+	}
+
+	err = buildPage(*outDir, "view/index.html", "view-all.html", "/view/", tmpls, pages, util.ExecuteTemplate)
+	if err != nil {
+		return err
+	}
+
+	for _, summary := range *pages {
+		page, err := post.GetPage(ctx, db, summary.ID)
+		if err != nil {
+			return err
+		}
+
+		data, err := newViewData(ctx, db, page, post.DefaultLang, public)
+		if err != nil {
+			return err
+		}
+
+		out := "view/" + strconv.Itoa(page.ID) + ".html"
+		err = buildPage(*outDir, out, "view.html", "/view/", tmpls, data, util.ExecuteTemplateHTML)
+		if err != nil {
+			return err
+		}
+	}
+
+	tools, err := fs.Glob(public, "tools/*.html")
+	if err != nil {
+		return err
+	}
+	for _, tool := range tools {
+		err = buildPage(*outDir, tool, tool, "/tools/", tmpls, nil, util.ExecuteTemplate)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Statiskā lapa ar %d ieteikumiem saglabāta '%s'\n", len(*pages), *outDir)
+	return nil
+}
+
+type executeFunc func(w io.Writer, r *http.Request, filename string, tmpls *util.Templates, data any) error
+
+func buildPage(outDir string, out string, filename string, urlPath string, tmpls *util.Templates, data any, execute executeFunc) error {
+	var err error
+
+	tmpl := newTmplData(urlPath)
+	tmpl.Data = data
+	tmpl.Static = true
+
+	var buf bytes.Buffer
+	err = execute(&buf, nil, filename, tmpls, &tmpl)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	out = filepath.Join(outDir, filepath.FromSlash(out))
+	err = os.MkdirAll(filepath.Dir(out), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(out, rewriteLinks(buf.Bytes()), 0644)
+}
+
+var hrefRe *regexp.Regexp = regexp.MustCompile(`href="(/[^"]*)"`)
+var viewLinkRe *regexp.Regexp = regexp.MustCompile(`^/view/([0-9]+)$`)
+var toolLinkRe *regexp.Regexp = regexp.MustCompile(`^/tools/([a-z0-9-]+)$`)
+
+// Point links to server routes at the files buildCmd() writes for them
+func rewriteLinks(html []byte) []byte {
+	return hrefRe.ReplaceAllFunc(html, func(match []byte) []byte {
+		link := string(hrefRe.FindSubmatch(match)[1])
+		path, fragment, _ := strings.Cut(link, "#")
+		if fragment != "" {
+			fragment = "#" + fragment
+		}
+
+		switch {
+		case path == "/view/":
+			path = "/view/index.html"
+		case viewLinkRe.MatchString(path):
+			path = viewLinkRe.ReplaceAllString(path, "/view/$1.html")
+		case toolLinkRe.MatchString(path):
+			path = toolLinkRe.ReplaceAllString(path, "/tools/$1.html")
+		default:
+			return match
+		}
+
+		return []byte(`href="` + path + fragment + `"`)
+	})
+}
+
+// Copy everything from the public directory except the templates and the
+// HTML files, which are all pages rendered by buildPage()
+func copyAssets(public fs.FS, publicDir string, outDir string) error {
+	var err error
+
+	// Don't copy the output into itself when it's in the public directory
+	publicAbs, err := filepath.Abs(publicDir)
+	if err != nil {
+		return err
+	}
+	outAbs, err := filepath.Abs(outDir)
+	if err != nil {
+		return err
+	}
+	outRel, err := filepath.Rel(publicAbs, outAbs)
+	if err != nil {
+		return err
+	}
+	outRel = filepath.ToSlash(outRel)
+
+	return fs.WalkDir(public, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path == "tmpl" || path == outRel {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) == ".html" {
+			return nil
+		}
+
+		return copyFile(public, path, filepath.Join(outDir, filepath.FromSlash(path)))
+	})
+}
+
+func copyFile(fsys fs.FS, src string, dst string) error {
+	var err error
+
+	in, err := fsys.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	err = os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
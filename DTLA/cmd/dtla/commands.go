@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"dtla/internal/database"
+	"dtla/internal/i18n"
+	"dtla/internal/migrate"
+	"dtla/internal/util"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Language of flag help texts and usage lines, from the locale environment variables
+var cliLang string = i18n.EnvLang()
+
+// Subcommands run instead of the web server when the first argument matches.
+// Each gets the arguments after its name and parses its own flags.
+var commands = map[string]func(args []string) error{
+	"backup":      backupCmd,
+	"build":       buildCmd,
+	"export":      exportCmd,
+	"import":      importCmd,
+	"migrate":     migrateCmd,
+	"precompress": precompressCmd,
+	"restore":     restoreCmd,
+}
+
+// Returns true if os.Args named a subcommand, which has then been run
+func runCommand() (bool, error) {
+	if len(os.Args) < 2 {
+		return false, nil
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		return false, nil
+	}
+
+	return true, cmd(os.Args[2:])
+}
+
+func commandsUsage() {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "\n%s\n", i18n.T(cliLang, "usage.commands", filepath.Base(os.Args[0])))
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", name)
+	}
+}
+
+// Open the database and bring its schema up to date,
+// or only check that it is when `migrateUp` is false
+func openDB(name string, migrateUp bool) (*sql.DB, error) {
+	var err error
+
+	db, err := database.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if migrateUp {
+		var applied []migrate.Migration
+		applied, err = migrate.Up(db)
+		for _, m := range applied {
+			util.LogInfof("Pielietota migrācija %04d_%s\n", m.Version, m.Name)
+		}
+	} else {
+		err = migrate.Check(db)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Like openDB, but a missing file is an error instead of a new database,
+// for commands that only work with an existing one
+func openExistingDB(name string, migrateUp bool) (*sql.DB, error) {
+	var err error
+
+	_, err = os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return openDB(name, migrateUp)
+}
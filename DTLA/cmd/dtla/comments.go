@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"dtla/internal/apperr"
+	"dtla/internal/comment"
+	"dtla/internal/i18n"
+	"dtla/internal/post"
+	"dtla/internal/render"
+	"dtla/internal/util"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// A post with what's shown below it
+type viewData struct {
+	*post.Page
+	Comments []*comment.Comment `json:"comments"`
+
+	// Language the post is shown in, post.DefaultLang when it has no translation
+	ContentLang string `json:"contentLang"`
+	// Set when the post has no translation into the language asked for
+	Untranslated bool `json:"untranslated"`
+	// For editors only
+	Translations []post.TranslationStatus `json:"translations"`
+
+	// Headings in the body, shown as a table of contents
+	TOC []render.Heading `json:"toc"`
+
+	// Rendered from the editor form, nothing is saved and there are no comments
+	Preview bool `json:"preview"`
+
+	// Put in the comment form for the spam heuristics
+	CommentTime int64 `json:"commentTime"`
+	// "pending" or "ok" after a comment was submitted, otherwise the key of why it was rejected
+	CommentStatus string `json:"commentStatus"`
+	// From the catalog for that key, empty for unknown ones
+	CommentError string `json:"commentError"`
+}
+
+// Translates the post into lang if it can and expands its shortcodes
+func newViewData(ctx context.Context, db *sql.DB, page *post.Page, lang string, public fs.FS) (*viewData, error) {
+	var err error
+
+	data := viewData{
+		Page:        page,
+		ContentLang: lang,
+		CommentTime: time.Now().Unix(),
+	}
+
+	translated, err := page.Translate(ctx, db, lang)
+	if err != nil {
+		return nil, err
+	}
+	if !translated {
+		data.ContentLang = post.DefaultLang
+		data.Untranslated = true
+	}
+
+	data.TOC = renderBody(page, lang, public)
+
+	data.Comments, err = comment.GetApproved(ctx, db, page.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// Turn the stored body into what's shown to a visitor reading the site in lang,
+// used for both saved posts and previews
+func renderBody(page *post.Page, lang string, public fs.FS) []render.Heading {
+	var toc []render.Heading
+
+	body, err := render.Shortcodes(page.Body, lang, public)
+	if errors.Is(err, render.ErrMissing) {
+		// Still shown, with placeholders for the missing files
+		util.LogError(fmt.Sprintf("Ieteikums %d: %s", page.ID, err.Error()))
+		page.Body = body
+	} else if err != nil {
+		util.LogError(fmt.Sprintf("Ieteikums %d: %s", page.ID, err.Error()))
+	} else {
+		page.Body = body
+	}
+
+	page.Body, toc = render.Headings(page.Body, lang)
+	return toc
+}
+
+func commentHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	postID, err := strconv.Atoi(r.URL.Path[len("/comment/"):])
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	// Make sure the post exists and isn't in the trash
+	_, err = hd.sstate.Posts.Get(r.Context(), postID)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	c := comment.Comment{
+		PostID: postID,
+		Author: r.PostFormValue("comment-author"),
+		Body:   r.PostFormValue("comment-body"),
+	}
+	c.IP, _, err = net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		c.IP = r.RemoteAddr
+	}
+
+	// Logged in users don't go through moderation
+	if hd.tmpl.Auth.Status == util.ASOk {
+		c.Author = hd.tmpl.Auth.User
+		c.UserID = hd.tmpl.Auth.ID
+		c.Status = comment.StatusApproved
+	}
+
+	err = c.Validate()
+	if err == nil && c.Status != comment.StatusApproved {
+		renderedUnix, _ := strconv.ParseInt(r.PostFormValue("comment-time"), 10, 64)
+		err = c.CheckSpam(r.Context(), hd.sstate.DB, comment.Form{
+			Honeypot: r.PostFormValue("comment-website"),
+			Rendered: time.Unix(renderedUnix, 0),
+		})
+	}
+	if errors.Is(err, comment.ErrSpam) {
+		// Don't tell bots that they were caught
+		util.LogInfof("Komentārs no %s atmests kā surogātpasts\n", c.IP)
+		commentRedirect(w, r, postID, "pending")
+		return
+	}
+	if key, ok := commentErrorKeys[err]; ok {
+		commentRedirect(w, r, postID, key)
+		return
+	}
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	err = c.Insert(r.Context(), hd.sstate.DB)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	if c.Status == comment.StatusApproved {
+		commentRedirect(w, r, postID, "ok")
+	} else {
+		commentRedirect(w, r, postID, "pending")
+	}
+}
+
+func commentRedirect(w http.ResponseWriter, r *http.Request, postID int, status string) {
+	http.Redirect(w, r, "/view/"+strconv.Itoa(postID)+"?comment="+url.QueryEscape(status)+"#comments", http.StatusSeeOther)
+}
+
+// Rejected comments are redirected back to the post with a fixed key instead
+// of the message, so a link can't make the page show text of its choosing
+var commentErrorKeys map[error]string = map[error]string{
+	comment.ErrEmpty:     "empty",
+	comment.ErrAuthor:    "author",
+	comment.ErrLong:      "long",
+	comment.ErrLinks:     "links",
+	comment.ErrRate:      "rate",
+	comment.ErrDuplicate: "duplicate",
+}
+
+// The message for a key from commentErrorKeys, empty for anything else
+func commentErrorMsg(lang string, key string) string {
+	switch key {
+	case "empty", "rate", "duplicate":
+		return i18n.T(lang, "comment."+key)
+	case "author":
+		return i18n.T(lang, "comment.author", comment.MaxAuthorLen)
+	case "long":
+		return i18n.T(lang, "comment.long", comment.MaxBodyLen)
+	case "links":
+		return i18n.T(lang, "comment.links", comment.MaxLinks)
+	}
+	return ""
+}
+
+// Pending comments together with the titles of their posts
+type moderationItem struct {
+	*comment.Comment
+	PostTitle string `json:"postTitle"`
+}
+
+func moderationHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.moderate")))
+		return
+	}
+
+	pending, err := comment.GetPending(r.Context(), hd.sstate.DB)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	var items []moderationItem
+	for _, c := range pending {
+		item := moderationItem{Comment: c}
+		page, err := hd.sstate.Posts.Get(r.Context(), c.PostID)
+		if err == nil {
+			item.PostTitle = page.Title
+		}
+		items = append(items, item)
+	}
+
+	hd.tmpl.Data = items
+	hd.tmpl.URLPath = "/moderation/"
+	err = util.ExecuteTemplate(w, r, "moderation.html", hd.sstate.Tmpl, &hd.tmpl)
+	if err != nil {
+		util.LogHTTPError(w, err)
+		return
+	}
+}
+
+func moderationApproveHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	moderate(w, r, hd, "/moderation/approve/", comment.StatusApproved)
+}
+
+func moderationRejectHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	moderate(w, r, hd, "/moderation/reject/", comment.StatusRejected)
+}
+
+func moderate(w http.ResponseWriter, r *http.Request, hd *handlerData, prefix string, status int) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.moderate")))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len(prefix):])
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	err = comment.SetStatus(r.Context(), hd.sstate.DB, id, status)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	http.Redirect(w, r, "/moderation/", http.StatusSeeOther)
+}
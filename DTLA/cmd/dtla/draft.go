@@ -0,0 +1,78 @@
+package main
+
+import (
+	"database/sql"
+	"dtla/internal/apperr"
+	"dtla/internal/i18n"
+	"dtla/internal/post"
+	"dtla/internal/util"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Autosave the edit form, called by js/edit.js.
+// Answers with JSON so the editor can tell when the session has expired.
+func draftHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusUnauthorized, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.api.unauthorized")))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len("/draft/"):])
+	if err != nil {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusBadRequest, err)
+		return
+	}
+
+	_, err = hd.sstate.Posts.Get(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusNotFound, apperr.New(apperr.NotFound, i18n.T(hd.tmpl.Lang, "err.post.not-found")))
+		return
+	}
+	if err != nil {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusInternalServerError, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, post.MaxBodyLen+64<<10)
+	d := post.Draft{PostID: id, UserID: hd.tmpl.Auth.ID}
+	err = d.LoadForm(r)
+	if err != nil {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusBadRequest, err)
+		return
+	}
+
+	err = d.Save(r.Context(), hd.sstate.DB)
+	if err != nil {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusInternalServerError, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, map[string]any{"saved": d.Saved})
+}
+
+func draftDiscardHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.edit")))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len("/draft/discard/"):])
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	err = post.DeleteDraft(r.Context(), hd.sstate.DB, id, hd.tmpl.Auth.ID)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	http.Redirect(w, r, "/edit/"+strconv.Itoa(id), http.StatusSeeOther)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"dtla/internal/apperr"
+	"dtla/internal/editlock"
+	"dtla/internal/i18n"
+	"dtla/internal/post"
+	"dtla/internal/util"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The edit page sends a heartbeat every 30 seconds, a lock without
+// one for this long is treated as released
+const editLockTTL = 2 * time.Minute
+
+// Someone else has the post open in the editor
+type lockedData struct {
+	Page *post.Page    `json:"page"`
+	Lock editlock.Lock `json:"lock"`
+}
+
+func editLocked(w http.ResponseWriter, r *http.Request, hd *handlerData, page *post.Page, lock editlock.Lock) {
+	var err error
+
+	hd.tmpl.Data = &lockedData{Page: page, Lock: lock}
+	hd.tmpl.URLPath = "/edit/"
+	util.WriteTemplateHeader(w, r, http.StatusConflict)
+	err = util.ExecuteTemplate(w, r, "locked.html", hd.sstate.Tmpl, &hd.tmpl)
+	if err != nil {
+		util.LogError(err.Error())
+		return
+	}
+}
+
+func editTakeOverHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.edit")))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len("/edit/takeover/"):])
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	hd.sstate.EditLocks.TakeOver(id, hd.tmpl.Auth.ID, hd.tmpl.Auth.User)
+	http.Redirect(w, r, "/edit/"+strconv.Itoa(id), http.StatusSeeOther)
+}
+
+// Called by js/edit.js while the edit page is open, answers with 409
+// when the lock was taken over so the editor can warn about it
+func editHeartbeatHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusUnauthorized, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.api.unauthorized")))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len("/edit/heartbeat/"):])
+	if err != nil {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusBadRequest, err)
+		return
+	}
+
+	lock, ok := hd.sstate.EditLocks.Heartbeat(id, hd.tmpl.Auth.ID, hd.tmpl.Auth.User)
+	if !ok {
+		util.WriteJSONError(w, hd.tmpl.Lang, http.StatusConflict, lockError(hd.tmpl.Lang, lock))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Sent with navigator.sendBeacon() when the edit page is closed
+func editReleaseHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len("/edit/release/"):])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	hd.sstate.EditLocks.Release(id, hd.tmpl.Auth.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func lockError(lang string, lock editlock.Lock) error {
+	return apperr.New(apperr.Conflict, i18n.T(lang, "err.locked", lock.User, lock.Since.Format("15:04")))
+}
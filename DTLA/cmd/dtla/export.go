@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"dtla/internal/i18n"
+	"dtla/internal/post"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func exportCmd(args []string) error {
+	var err error
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbName := fs.String("db", "db", i18n.T(cliLang, "flag.db"))
+	outDir := fs.String("out", "posts", i18n.T(cliLang, "flag.export.out"))
+	prune := fs.Bool("prune", false, i18n.T(cliLang, "flag.export.prune"))
+	fs.Parse(args)
+
+	// Exporting only reads, an outdated database has to be migrated first
+	db, err := openExistingDB(*dbName, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	pages, err := post.GetAllPages(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(*outDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	written := make(map[string]bool)
+	for _, summary := range *pages {
+		page, err := post.GetPage(ctx, db, summary.ID)
+		if err != nil {
+			return err
+		}
+
+		name := post.FileName(page)
+		err = post.WriteFile(filepath.Join(*outDir, name), page)
+		if err != nil {
+			return err
+		}
+		written[name] = true
+	}
+
+	// Files of posts that were deleted or renamed since the last export
+	if *prune {
+		old, err := post.ListFiles(*outDir)
+		if err != nil {
+			return err
+		}
+		for _, name := range old {
+			if written[name] {
+				continue
+			}
+			err = os.Remove(filepath.Join(*outDir, name))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("Eksportēti %d ieteikumi uz '%s'\n", len(written), *outDir)
+	return nil
+}
+
+func importCmd(args []string) error {
+	var err error
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dbName := fs.String("db", "db", i18n.T(cliLang, "flag.db"))
+	inDir := fs.String("in", "posts", i18n.T(cliLang, "flag.import.in"))
+	prune := fs.Bool("prune", false, i18n.T(cliLang, "flag.import.prune"))
+	fs.Parse(args)
+
+	names, err := post.ListFiles(*inDir)
+	if err != nil {
+		return err
+	}
+
+	var pages []*post.Page
+	for _, name := range names {
+		page, err := post.ReadFile(filepath.Join(*inDir, name))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		pages = append(pages, page)
+	}
+
+	db, err := openExistingDB(*dbName, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	existing, err := post.GetAllPages(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	imported := make(map[int]bool)
+	for _, page := range pages {
+		err = page.Put(ctx, tx)
+		if err != nil {
+			return err
+		}
+		imported[page.ID] = true
+	}
+
+	if *prune {
+		for _, page := range *existing {
+			if imported[page.ID] {
+				continue
+			}
+			err = post.Trash(ctx, tx, page.ID, "import")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Importēti %d ieteikumi no '%s'\n", len(pages), *inDir)
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"dtla/internal/feed"
+	"dtla/internal/util"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+const feedTitle = "Datorsistēmu un tīklu loģiskā aizsardzība"
+
+func feedAtomHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	serveFeed(w, r, hd, "/feed.atom", "application/atom+xml; charset=utf-8", feed.WriteAtom)
+}
+
+func feedRSSHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	serveFeed(w, r, hd, "/feed.rss", "application/rss+xml; charset=utf-8", feed.WriteRSS)
+}
+
+// Build the feed of all posts, or only the ones with the tag from the "tag" query
+// parameter, and serve it with http.ServeContent() so conditional requests work
+func serveFeed(w http.ResponseWriter, r *http.Request, hd *handlerData, path string, contentType string, write func(io.Writer, *feed.Feed) error) {
+	var err error
+
+	pages, err := hd.sstate.Posts.All(r.Context())
+	if err != nil {
+		util.LogHTTPError(w, err)
+		return
+	}
+
+	baseURL := hd.sstate.BaseURL()
+	tag := r.URL.Query().Get("tag")
+
+	f := feed.Feed{
+		Title:    feedTitle,
+		Desc:     "Servera aizsardzības ieteikumi",
+		Link:     baseURL + "/view/",
+		SelfLink: baseURL + path,
+	}
+	if tag != "" {
+		f.Title += " - " + tag
+		f.SelfLink += "?tag=" + url.QueryEscape(tag)
+	}
+
+	sort.Slice(*pages, func(i, j int) bool {
+		return (*pages)[i].Updated.After((*pages)[j].Updated)
+	})
+
+	for _, page := range *pages {
+		if tag != "" && !page.HasTag(tag) {
+			continue
+		}
+
+		if page.Updated.After(f.Updated) {
+			f.Updated = page.Updated
+		}
+
+		f.Entries = append(f.Entries, feed.Entry{
+			Title:      page.Title,
+			Summary:    page.Desc,
+			Link:       baseURL + "/view/" + strconv.Itoa(page.ID),
+			Categories: page.Tags,
+			Published:  page.Created,
+			Updated:    page.Updated,
+		})
+	}
+
+	var buf bytes.Buffer
+	err = write(&buf, &f)
+	if err != nil {
+		util.LogHTTPError(w, err)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=600")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, "", f.Updated, bytes.NewReader(buf.Bytes()))
+}
@@ -3,12 +3,19 @@ package main
 import (
 	"crypto/rand"
 	"database/sql"
+	"dtla/internal/apperr"
+	"dtla/internal/comment"
+	"dtla/internal/i18n"
+	"dtla/internal/media"
 	"dtla/internal/post"
+	"dtla/internal/render"
 	"dtla/internal/util"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"time"
@@ -28,17 +35,14 @@ func makeHandler(fn func(http.ResponseWriter, *http.Request, *handlerData), ssta
 
 		var hd handlerData
 
-		hd.tmpl.Auth.ASDefault = util.ASDefault
-		hd.tmpl.Auth.ASError = util.ASError
-		hd.tmpl.Auth.ASOk = util.ASOk
-
 		hd.cleanPath, err = util.CleanPath(r.URL.Path)
 		if err != nil {
 			util.LogHTTPError(w, err)
 			return
 		}
 		hd.sstate = sstate
-		hd.tmpl.URLPath = r.URL.Path
+		hd.tmpl = newTmplData(r.URL.Path)
+		hd.tmpl.Lang = requestLang(r)
 
 		if !wantAuth {
 			fn(w, r, &hd)
@@ -63,10 +67,15 @@ func makeHandler(fn func(http.ResponseWriter, *http.Request, *handlerData), ssta
 			return
 		}
 
-		err = getUserData(r, hd.sstate.DB, &hd.tmpl.Auth)
+		err = getUserData(r, hd.sstate.Users, &hd.tmpl.Auth)
 		if err != nil {
 			if errors.Is(err, util.ErrSessionExpired) {
-				util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, err.Error())
+				// For js/edit.js autosaving a draft
+				if util.WantsJSON(r) {
+					util.WriteJSONError(w, hd.tmpl.Lang, http.StatusUnauthorized, err)
+					return
+				}
+				util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 				return
 			}
 			handlerAuthError(fn, w, r, &hd, err)
@@ -80,23 +89,47 @@ func makeHandler(fn func(http.ResponseWriter, *http.Request, *handlerData), ssta
 		}
 
 		hd.tmpl.Auth.Status = util.ASOk
+
+		hd.tmpl.PendingComments, err = comment.CountPending(r.Context(), hd.sstate.DB)
+		if err != nil {
+			util.LogError(err.Error())
+		}
+
 		fn(w, r, &hd)
 	}
 }
 
+func newTmplData(urlPath string) util.TmplData {
+	var tmpl util.TmplData
+
+	tmpl.URLPath = urlPath
+	tmpl.Auth.ASDefault = util.ASDefault
+	tmpl.Auth.ASError = util.ASError
+	tmpl.Auth.ASOk = util.ASOk
+	tmpl.Lang = post.DefaultLang
+
+	return tmpl
+}
+
 // Call handler with Auth.Status and Auth.Error set
 // so they can be checked in handler and templates
 func handlerAuthError(fn func(http.ResponseWriter, *http.Request, *handlerData), w http.ResponseWriter, r *http.Request, hd *handlerData, err error) {
-	hd.tmpl.Auth.Status = util.ASError
-	hd.tmpl.Auth.Error = err.Error()
+	// Details of a broken session are only for the log
 	util.LogError(err.Error())
+	hd.tmpl.Auth.Status = util.ASError
+	hd.tmpl.Auth.Error = i18n.T(hd.tmpl.Lang, "err.auth.session")
 	fn(w, r, hd)
 }
 
 func rootHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 	var err error
 
-	err = util.ExecuteTemplate(w, r, hd.cleanPath, *hd.sstate.TmplDir, &hd.tmpl)
+	// Nothing is written when the page doesn't exist, error.html can still be shown
+	err = util.ExecuteTemplate(w, r, hd.cleanPath, hd.sstate.Tmpl, &hd.tmpl)
+	if apperr.KindOf(err) == apperr.NotFound {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
 	if err != nil {
 		util.LogHTTPError(w, err)
 		return
@@ -106,13 +139,20 @@ func rootHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 func viewAllHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 	var err error
 
-	hd.tmpl.Data, err = post.GetAllPages(hd.sstate.DB)
+	pages, err := hd.sstate.Posts.All(r.Context())
 	if err != nil {
-		util.LogHTTPError(w, err)
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
 
-	err = util.ExecuteTemplate(w, r, "view-all.html", *hd.sstate.TmplDir, &hd.tmpl)
+	err = post.TranslateAll(r.Context(), hd.sstate.DB, pages, hd.tmpl.Lang)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+	hd.tmpl.Data = pages
+
+	err = util.ExecuteTemplate(w, r, "view-all.html", hd.sstate.Tmpl, &hd.tmpl)
 	if err != nil {
 		util.LogHTTPError(w, err)
 		return
@@ -124,46 +164,99 @@ func viewHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 
 	pageID, err := strconv.Atoi(r.URL.Path[len("/view/"):])
 	if err != nil {
-		util.LogHTTPError(w, err)
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
 
-	hd.tmpl.Data, err = post.GetPage(hd.sstate.DB, pageID)
+	page, err := hd.sstate.Posts.Get(r.Context(), pageID)
 	if err != nil {
-		util.LogHTTPError(w, err)
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	data, err := newViewData(r.Context(), hd.sstate.DB, page, hd.tmpl.Lang, hd.sstate.Public)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
+	if hd.tmpl.Auth.Status == util.ASOk {
+		data.Translations, err = post.GetTranslationStatus(r.Context(), hd.sstate.DB, page)
+		if err != nil {
+			util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+			return
+		}
+	}
+	data.CommentStatus = r.URL.Query().Get("comment")
+	data.CommentError = commentErrorMsg(hd.tmpl.Lang, data.CommentStatus)
+	hd.tmpl.Data = data
 
 	hd.tmpl.URLPath = "/view/"
-	err = util.ExecuteTemplateHTML(w, r, "view.html", *hd.sstate.TmplDir, &hd.tmpl)
+	err = util.ExecuteTemplateHTML(w, r, "view.html", hd.sstate.Tmpl, &hd.tmpl)
 	if err != nil {
 		util.LogHTTPError(w, err)
 		return
 	}
 }
 
+// The post being edited and files that can be inserted into it
+type editData struct {
+	*post.Page
+	Media []*media.File `json:"media"`
+
+	// An autosaved draft that wasn't saved as the post
+	Draft *post.Draft `json:"draft"`
+	// Set when the form is filled from the draft instead of the post
+	DraftApplied bool `json:"draftApplied"`
+}
+
 func editHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 	var err error
 
 	if hd.tmpl.Auth.Status != util.ASOk {
-		util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, "Nav atļauts rediģēt ieteikumu")
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.edit")))
 		return
 	}
 
 	id, err := strconv.Atoi(r.URL.Path[len("/edit/"):])
 	if err != nil {
-		util.LogHTTPError(w, err)
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
 
-	hd.tmpl.Data, err = post.GetPage(hd.sstate.DB, id)
+	var data editData
+	data.Page, err = hd.sstate.Posts.Get(r.Context(), id)
 	if err != nil {
-		util.LogHTTPError(w, err)
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	lock, ok := hd.sstate.EditLocks.Acquire(id, hd.tmpl.Auth.ID, hd.tmpl.Auth.User)
+	if !ok {
+		editLocked(w, r, hd, data.Page, lock)
 		return
 	}
 
+	data.Draft, err = post.GetDraft(r.Context(), hd.sstate.DB, id, hd.tmpl.Auth.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		data.Draft = nil
+	} else if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+	if data.Draft != nil && r.URL.Query().Get("draft") == "apply" {
+		data.Draft.Apply(data.Page)
+		data.DraftApplied = true
+	}
+
+	data.Media, err = media.List(filepath.Join(*hd.sstate.PublicDir, mediaDir), mediaURL)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	hd.tmpl.Data = &data
 	hd.tmpl.URLPath = "/edit/"
-	err = util.ExecuteTemplate(w, r, "edit.html", *hd.sstate.TmplDir, &hd.tmpl)
+	err = util.ExecuteTemplate(w, r, "edit.html", hd.sstate.Tmpl, &hd.tmpl)
 	if err != nil {
 		util.LogHTTPError(w, err)
 		return
@@ -174,7 +267,7 @@ func saveHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 	var err error
 
 	if hd.tmpl.Auth.Status != util.ASOk {
-		util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, "Nav atļauts rediģēt ieteikumu")
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.edit")))
 		return
 	}
 
@@ -182,79 +275,245 @@ func saveHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 
 	err = page.LoadForm(r)
 	if err != nil {
-		util.LogHTTPError(w, err)
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
 
-	err = page.Save(hd.sstate.DB)
+	err = checkShortcodes(page.Body, hd.sstate.Public)
 	if err != nil {
-		util.LogHTTPError(w, err)
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
 
+	err = hd.sstate.Posts.Save(r.Context(), &page)
+	if errors.Is(err, post.ErrConflict) {
+		saveConflict(w, r, hd, &page)
+		return
+	}
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	// The draft is in the post now
+	err = post.DeleteDraft(r.Context(), hd.sstate.DB, page.ID, hd.tmpl.Auth.ID)
+	if err != nil {
+		util.LogError(err.Error())
+	}
+	hd.sstate.EditLocks.Release(page.ID, hd.tmpl.Auth.ID)
+
 	http.Redirect(w, r, "/view/"+strconv.Itoa(page.ID), http.StatusSeeOther)
 }
 
+// Reject bodies with shortcodes that can't be expanded before they are saved
+func checkShortcodes(body string, public fs.FS) error {
+	_, err := render.Shortcodes(body, post.DefaultLang, public)
+	if err != nil && !errors.Is(err, render.ErrMissing) && !errors.Is(err, render.ErrArgs) {
+		// Quoting errors are the editor's to fix, so they're shown
+		return apperr.NewT(apperr.BadRequest, "err.shortcode", err.Error())
+	}
+	return err
+}
+
+// Both versions of a post that two editors saved
+type conflictData struct {
+	Mine   *post.Page `json:"mine"`
+	Theirs *post.Page `json:"theirs"`
+}
+
+// Show the submitted and the current version of the post with a form
+// for saving a merge of them on top of the current version
+func saveConflict(w http.ResponseWriter, r *http.Request, hd *handlerData, mine *post.Page) {
+	var err error
+
+	theirs, err := hd.sstate.Posts.Get(r.Context(), mine.ID)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	hd.tmpl.Data = &conflictData{Mine: mine, Theirs: theirs}
+	hd.tmpl.URLPath = "/edit/"
+	util.WriteTemplateHeader(w, r, http.StatusConflict)
+	err = util.ExecuteTemplate(w, r, "conflict.html", hd.sstate.Tmpl, &hd.tmpl)
+	if err != nil {
+		util.LogError(err.Error())
+		return
+	}
+}
+
 func toolsHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 	var err error
 
 	if r.URL.Path == "/tools/sockets" && runtime.GOOS == "windows" {
-		util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, "Nav atbalstīts uz Windows servera")
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.BadRequest, i18n.T(hd.tmpl.Lang, "err.windows")))
 		return
 	}
 
 	filename := hd.cleanPath + ".html"
 	hd.tmpl.URLPath = "/tools/"
-	err = util.ExecuteTemplate(w, r, filename, *hd.sstate.TmplDir, &hd.tmpl)
+	err = util.ExecuteTemplate(w, r, filename, hd.sstate.Tmpl, &hd.tmpl)
 	if err != nil {
-		util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, err.Error())
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 	}
 }
 
+// Ask for confirmation, the form posts to deletePostHandler()
 func deleteHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 	var err error
 
 	if hd.tmpl.Auth.Status != util.ASOk {
-		util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, "Nav atļauts dzēst ieteikumu")
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.delete")))
 		return
 	}
 
 	id, err := strconv.Atoi(r.URL.Path[len("/delete/"):])
 	if err != nil {
-		util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, err.Error())
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
 
-	_, err = hd.sstate.DB.Exec("DELETE FROM posts WHERE id IS ?", id)
+	hd.tmpl.Data, err = hd.sstate.Posts.Get(r.Context(), id)
 	if err != nil {
-		util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, err.Error())
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
 
-	http.Redirect(w, r, "/view/", http.StatusTemporaryRedirect)
+	hd.tmpl.URLPath = "/view/"
+	err = util.ExecuteTemplate(w, r, "delete.html", hd.sstate.Tmpl, &hd.tmpl)
+	if err != nil {
+		util.LogHTTPError(w, err)
+		return
+	}
+}
+
+// Move the post to the trash
+func deletePostHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.delete")))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len("/delete/"):])
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	err = hd.sstate.Posts.Trash(r.Context(), id, hd.tmpl.Auth.User)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	http.Redirect(w, r, "/view/", http.StatusSeeOther)
+}
+
+// The new post form, filled out again with an error or duplicate title warning when submitting fails
+type newData struct {
+	Page      *post.Page    `json:"page"`
+	Templates *[]*post.Page `json:"templates"`
+	Duplicate bool          `json:"duplicate"`
+	Error     string        `json:"error,omitempty"`
 }
 
 func newHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 	var err error
 
 	if hd.tmpl.Auth.Status != util.ASOk {
-		util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, "Nav atļauts izveidot jaunu ieteikumu")
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.new")))
 		return
 	}
 
-	res, err := hd.sstate.DB.Exec("INSERT INTO posts (title, desc, body) VALUES ('Virsraksts', 'Apraksts', '<p>Saturs</p>')")
+	var data newData
+	data.Page = new(post.Page)
+
+	// Start from an existing post used as a template
+	from := r.URL.Query().Get("from")
+	if from != "" {
+		var id int
+		id, err = strconv.Atoi(from)
+		if err != nil {
+			util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+			return
+		}
+
+		data.Page, err = hd.sstate.Posts.Get(r.Context(), id)
+		if err != nil {
+			util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+			return
+		}
+		data.Page.ID = 0
+	}
+
+	executeNew(w, r, hd, &data, http.StatusOK)
+}
+
+func newPostHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.new")))
+		return
+	}
+
+	var data newData
+	data.Page = new(post.Page)
+
+	err = data.Page.LoadNewForm(r)
+	if err == nil {
+		err = checkShortcodes(data.Page.Body, hd.sstate.Public)
+	}
+	if err != nil {
+		data.Error = util.ErrorMessage(err, hd.tmpl.Lang)
+		executeNew(w, r, hd, &data, http.StatusBadRequest)
+		return
+	}
+
+	if r.PostFormValue("post-confirm-duplicate") == "" {
+		data.Duplicate, err = hd.sstate.Posts.TitleExists(r.Context(), data.Page.Title)
+		if err != nil {
+			util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+			return
+		}
+		if data.Duplicate {
+			executeNew(w, r, hd, &data, http.StatusOK)
+			return
+		}
+	}
+
+	err = hd.sstate.Posts.Insert(r.Context(), data.Page)
 	if err != nil {
-		util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, err.Error())
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
 
-	id, err := res.LastInsertId()
+	http.Redirect(w, r, "/view/"+strconv.Itoa(data.Page.ID), http.StatusSeeOther)
+}
+
+// The status is only written once the posts to use as templates are loaded,
+// so an error page can still be shown if that fails
+func executeNew(w http.ResponseWriter, r *http.Request, hd *handlerData, data *newData, status int) {
+	var err error
+
+	data.Templates, err = hd.sstate.Posts.All(r.Context())
 	if err != nil {
-		util.ExecuteTemplateError(w, r, *hd.sstate.TmplDir, &hd.tmpl, err.Error())
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
 
-	http.Redirect(w, r, "/edit/"+strconv.FormatInt(id, 10), http.StatusTemporaryRedirect)
+	hd.tmpl.Data = data
+	hd.tmpl.URLPath = "/view/"
+	if status != http.StatusOK {
+		util.WriteTemplateHeader(w, r, status)
+	}
+	err = util.ExecuteTemplate(w, r, "new.html", hd.sstate.Tmpl, &hd.tmpl)
+	if err != nil {
+		util.LogError(err.Error())
+		return
+	}
 }
 
 func loginHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
@@ -265,7 +524,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 		return
 	}
 
-	err = util.ExecuteTemplate(w, r, "login.html", *hd.sstate.TmplDir, &hd.tmpl)
+	err = util.ExecuteTemplate(w, r, "login.html", hd.sstate.Tmpl, &hd.tmpl)
 	if err != nil {
 		util.LogHTTPError(w, err)
 		return
@@ -277,7 +536,7 @@ func loginPostHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 
 	err = r.ParseForm()
 	if err != nil {
-		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, err)
+		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, hd.sstate.Tmpl, apperr.Wrap(apperr.BadRequest, err))
 		return
 	}
 
@@ -285,93 +544,79 @@ func loginPostHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 	pswd := r.PostFormValue("login-pswd")
 
 	if user == "" || pswd == "" {
-		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, errors.New("Lietotājvārds un parole nedrīkst būt neaizpildīti"))
+		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, hd.sstate.Tmpl, apperr.New(apperr.BadRequest, i18n.T(hd.tmpl.Lang, "err.login.empty")))
 		return
 	}
 
-	row := hd.sstate.DB.QueryRow("SELECT id FROM users WHERE user IS ?", user)
-	var id uint
-	err = row.Scan(&id)
+	u, err := hd.sstate.Users.ByName(r.Context(), user)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, errors.New("Lietotājs neeksistē"))
+			util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, hd.sstate.Tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.login.no-user")))
 			return
 		}
-		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, err)
+		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, hd.sstate.Tmpl, err)
 		return
 	}
+	id := u.ID
 
-	row = hd.sstate.DB.QueryRow("SELECT pswd FROM users WHERE id IS ?", id)
-	var pswdHashDB string
-	err = row.Scan(&pswdHashDB)
-	if err != nil {
-		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, err)
-		return
-	}
-
-	pswdHashDBBytes := []byte(pswdHashDB)
 	pswdBytes := []byte(pswd)
 
-	err = bcrypt.CompareHashAndPassword(pswdHashDBBytes, pswdBytes)
+	err = bcrypt.CompareHashAndPassword(u.PswdHash, pswdBytes)
 	if err != nil {
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, errors.New("Nepareiza parole"))
+			util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, hd.sstate.Tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.login.pswd")))
 			return
 		}
-		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, err)
+		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, hd.sstate.Tmpl, err)
 		return
 	}
 
 	var sidBytes []byte = make([]byte, 72)
 	_, err = rand.Read(sidBytes)
 	if err != nil {
-		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, err)
+		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, hd.sstate.Tmpl, err)
 		return
 	}
 
 	sidHashBytes, err := bcrypt.GenerateFromPassword(sidBytes, 6)
 	if err != nil {
-		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, err)
-		return
-	}
-
-	_, err = hd.sstate.DB.Exec("UPDATE users SET sid = ? WHERE id IS ?", string(sidHashBytes), id)
-	if err != nil {
-		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, err)
+		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, hd.sstate.Tmpl, err)
 		return
 	}
 
 	hd.tmpl.Auth.SStart = time.Now()
 	hd.tmpl.Auth.SAge = time.Second * 300
 
-	_, err = hd.sstate.DB.Exec("UPDATE users SET sstart = ?, sage = ? WHERE id IS ?", hd.tmpl.Auth.SStart.Unix(), int(hd.tmpl.Auth.SAge.Seconds()), id)
+	err = hd.sstate.Users.SetSession(r.Context(), id, sidHashBytes, hd.tmpl.Auth.SStart, hd.tmpl.Auth.SAge)
 	if err != nil {
-		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, *hd.sstate.TmplDir, err)
+		util.ExecuteTemplateLoginWithError(w, r, &hd.tmpl, hd.sstate.Tmpl, err)
 		return
 	}
 
 	// When checking sid in requests, hex will be transformed to []byte with hex.DecodeString()
 	// and compared using bcrypt.CompareHashAndPassword()
 	sidCookie := http.Cookie{
-		Name:   "sid",
-		MaxAge: int(hd.tmpl.Auth.SAge.Seconds()),
-		Value:  hex.EncodeToString(sidBytes),
+		Name:     "sid",
+		MaxAge:   int(hd.tmpl.Auth.SAge.Seconds()),
+		Value:    hex.EncodeToString(sidBytes),
+		SameSite: http.SameSiteLaxMode,
 	}
 	http.SetCookie(w, &sidCookie)
 
 	// bcrypt.GenerateFromPassword() uses a random salt so to compare the sid in later requests
 	// the id is needed to find the user for which to compare it against
 	idCookie := http.Cookie{
-		Name:   "id",
-		MaxAge: int(hd.tmpl.Auth.SAge.Seconds()),
-		Value:  fmt.Sprintf("%d", id),
+		Name:     "id",
+		MaxAge:   int(hd.tmpl.Auth.SAge.Seconds()),
+		Value:    fmt.Sprintf("%d", id),
+		SameSite: http.SameSiteLaxMode,
 	}
 	http.SetCookie(w, &idCookie)
 
 	w.Header().Set("Cache-Control", "no-store")
 	w.Header().Set("refresh", "1;url=/")
 	hd.tmpl.Auth.Status = util.ASOk
-	err = util.ExecuteTemplate(w, r, "login.html", *hd.sstate.TmplDir, &hd.tmpl)
+	err = util.ExecuteTemplate(w, r, "login.html", hd.sstate.Tmpl, &hd.tmpl)
 	if err != nil {
 		util.LogHTTPError(w, err)
 	}
@@ -398,13 +643,13 @@ func logoutHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 func getHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
 	var err error
 
-	err = util.ServeFile(w, r, hd.cleanPath)
+	err = util.ServeFile(w, r, hd.sstate.Public, hd.cleanPath)
 	if err != nil {
-		util.LogHTTPError(w, err)
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
 		return
 	}
 }
 
 func licenseHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
-	http.ServeFile(w, r, "LICENSE")
+	http.ServeFileFS(w, r, hd.sstate.Public, "LICENSE")
 }
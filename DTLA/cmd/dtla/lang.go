@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"dtla/internal/post"
+	"dtla/internal/util"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The language a page is read in is picked from, in order:
+// a language prefix in the path like /en/view/1, the "lang" cookie,
+// the Accept-Language header, and post.DefaultLang.
+// Opening a prefixed path also sets the cookie so links without
+// the prefix keep the language.
+
+type langKey struct{}
+
+const langCookie = "lang"
+
+// Strip a language prefix from the path before the request reaches the mux
+func langPrefixHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, lang := range post.Languages {
+			prefix := "/" + lang
+			if r.URL.Path != prefix && !strings.HasPrefix(r.URL.Path, prefix+"/") {
+				continue
+			}
+
+			setLangCookie(w, lang)
+			r = r.WithContext(context.WithValue(r.Context(), langKey{}, lang))
+			r.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+			if r.URL.Path == "" {
+				r.URL.Path = "/"
+			}
+			r.URL.RawPath = ""
+			break
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requestLang(r *http.Request) string {
+	lang, ok := r.Context().Value(langKey{}).(string)
+	if ok {
+		return lang
+	}
+
+	cookie, err := r.Cookie(langCookie)
+	if err == nil && post.ValidLang(cookie.Value) {
+		return cookie.Value
+	}
+
+	return acceptLang(r.Header.Get("Accept-Language"))
+}
+
+// The supported language with the highest quality in an Accept-Language header
+func acceptLang(header string) string {
+	type option struct {
+		lang string
+		q    float64
+	}
+
+	var options []option
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		// Only the primary subtag matters, en-GB is en
+		tag, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !post.ValidLang(tag) {
+			continue
+		}
+
+		q := 1.0
+		params = strings.TrimSpace(params)
+		if strings.HasPrefix(params, "q=") {
+			var err error
+			q, err = strconv.ParseFloat(params[len("q="):], 64)
+			if err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			options = append(options, option{tag, q})
+		}
+	}
+
+	if len(options) == 0 {
+		return post.DefaultLang
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].q > options[j].q
+	})
+	return options[0].lang
+}
+
+func setLangCookie(w http.ResponseWriter, lang string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     langCookie,
+		Value:    lang,
+		Path:     "/",
+		Expires:  time.Now().Add(365 * 24 * time.Hour),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Switch the language with the links in the navbar and go back to the page they were on
+func langHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	lang := r.URL.Path[len("/lang/"):]
+	if !post.ValidLang(lang) {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, post.ErrLang)
+		return
+	}
+
+	setLangCookie(w, lang)
+
+	ret := "/"
+	referer, err := url.Parse(r.Referer())
+	if err == nil && referer.Host == r.Host {
+		ret = localPath(referer.RequestURI(), "/")
+		// A prefix would set the language back
+		for _, l := range post.Languages {
+			if ret == "/"+l || strings.HasPrefix(ret, "/"+l+"/") {
+				ret = "/" + strings.TrimPrefix(ret[len("/"+l):], "/")
+				break
+			}
+		}
+	}
+	http.Redirect(w, r, ret, http.StatusSeeOther)
+}
+
+// Only local paths are allowed for redirects given by the client
+// so they can't be used as open redirects
+func localPath(path string, fallback string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return fallback
+	}
+	return path
+}
@@ -17,6 +17,14 @@ func main() {
 
 	log.SetFlags(log.Ltime | log.Llongfile)
 
+	ran, err := runCommand()
+	if ran {
+		if err != nil {
+			util.LogFatal(err.Error())
+		}
+		return
+	}
+
 	var sstate ServerState
 	err = (&sstate).Init()
 	if err != nil {
@@ -27,18 +35,51 @@ func main() {
 	sstate.mux.HandleFunc("GET /view/{$}", makeHandler(viewAllHandler, &sstate, true))
 	sstate.mux.HandleFunc("GET /view/", makeHandler(viewHandler, &sstate, true))
 	sstate.mux.HandleFunc("GET /edit/", makeHandler(editHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /edit/takeover/", makeHandler(editTakeOverHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /edit/heartbeat/", makeHandler(editHeartbeatHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /edit/release/", makeHandler(editReleaseHandler, &sstate, true))
 	sstate.mux.HandleFunc("POST /save/", makeHandler(saveHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /draft/", makeHandler(draftHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /draft/discard/", makeHandler(draftDiscardHandler, &sstate, true))
 	sstate.mux.HandleFunc("GET /tools/", makeHandler(toolsHandler, &sstate, true))
-	sstate.mux.HandleFunc("GET /new/", makeHandler(newHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /preview/{$}", makeHandler(previewHandler, &sstate, true))
+	sstate.mux.HandleFunc("GET /new/{$}", makeHandler(newHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /new/{$}", makeHandler(newPostHandler, &sstate, true))
 	sstate.mux.HandleFunc("GET /delete/", makeHandler(deleteHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /delete/", makeHandler(deletePostHandler, &sstate, true))
+	sstate.mux.HandleFunc("GET /trash/{$}", makeHandler(trashHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /trash/restore/", makeHandler(trashRestoreHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /trash/purge/", makeHandler(trashPurgeHandler, &sstate, true))
+	sstate.mux.HandleFunc("GET /media/{$}", makeHandler(mediaHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /media/upload", makeHandler(mediaUploadHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /media/delete/", makeHandler(mediaDeleteHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /comment/", makeHandler(commentHandler, &sstate, true))
+	sstate.mux.HandleFunc("GET /moderation/{$}", makeHandler(moderationHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /moderation/approve/", makeHandler(moderationApproveHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /moderation/reject/", makeHandler(moderationRejectHandler, &sstate, true))
+	sstate.mux.HandleFunc("GET /translate/", makeHandler(translateHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /translate/", makeHandler(translatePostHandler, &sstate, true))
+	sstate.mux.HandleFunc("GET /lang/", makeHandler(langHandler, &sstate, false))
 	sstate.mux.HandleFunc("GET /login", makeHandler(loginHandler, &sstate, true))
 	sstate.mux.HandleFunc("POST /login", makeHandler(loginPostHandler, &sstate, true))
 	sstate.mux.HandleFunc("GET /logout", makeHandler(logoutHandler, &sstate, true))
-	sstate.mux.HandleFunc("GET /LICENSE", makeHandler(licenseHandler, nil, false))
-	sstate.mux.HandleFunc("GET /", makeHandler(getHandler, nil, false))
+	sstate.mux.HandleFunc("GET /feed.atom", makeHandler(feedAtomHandler, &sstate, false))
+	sstate.mux.HandleFunc("GET /feed.rss", makeHandler(feedRSSHandler, &sstate, false))
+	sstate.mux.HandleFunc("GET /LICENSE", makeHandler(licenseHandler, &sstate, false))
+	sstate.mux.HandleFunc("GET /", makeHandler(getHandler, &sstate, false))
 	sstate.mux.Handle("GET /api/sockets", websocket.Handler(sockets.Handler))
+	sstate.mux.HandleFunc("GET /api/v1/posts", makeHandler(apiPostsHandler, &sstate, true))
+	sstate.mux.HandleFunc("POST /api/v1/posts", makeHandler(apiPostCreateHandler, &sstate, true))
+	sstate.mux.HandleFunc("GET /api/v1/posts/{id}", makeHandler(apiPostHandler, &sstate, true))
+	sstate.mux.HandleFunc("PUT /api/v1/posts/{id}", makeHandler(apiPostUpdateHandler, &sstate, true))
+	sstate.mux.HandleFunc("DELETE /api/v1/posts/{id}", makeHandler(apiPostDeleteHandler, &sstate, true))
 
 	go ListenShutdown(&sstate)
+	go PurgeTrash(&sstate)
+	go BackupDB(&sstate)
+	if *sstate.Dev {
+		go sstate.Tmpl.Watch(sstate.ctx, templateWatchInterval)
+	}
 
 	var httpProtocol string
 	if *sstate.TLS {
@@ -48,7 +89,11 @@ func main() {
 	}
 
 	fmt.Printf("%s serveris palaists uz %s:%s\n", httpProtocol, *sstate.HttpIP, *sstate.HttpPort)
-	fmt.Printf("Servē failus no '%s' un datubāzes '%s'\n", *sstate.PublicDir, *sstate.DBName)
+	if *sstate.Mem {
+		fmt.Printf("Servē iebūvētos failus un failus no '%s', ieteikumi un lietotāji tiek glabāti atmiņā\n", *sstate.PublicDir)
+	} else {
+		fmt.Printf("Servē iebūvētos failus un failus no '%s', datubāze '%s'\n", *sstate.PublicDir, *sstate.DBName)
+	}
 	if *sstate.TLS {
 		err = sstate.srv.ListenAndServeTLS(*sstate.TLSCert, *sstate.TLSPKey)
 	} else {
@@ -60,4 +105,7 @@ func main() {
 		return
 	}
 
+	// ListenAndServe() returns as soon as shutdown starts, wait for requests to finish
+	<-sstate.stopped
+
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"dtla/internal/apperr"
+	"dtla/internal/i18n"
+	"dtla/internal/media"
+	"dtla/internal/util"
+	"errors"
+	"net/http"
+	"path/filepath"
+)
+
+// Relative to the public directory, uploads are stored in -public on disk
+// and served from there as it's layered over the embedded files
+const mediaDir = "img/media"
+const mediaURL = "/img/media/"
+
+func mediaHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.media.view")))
+		return
+	}
+
+	hd.tmpl.Data, err = media.List(filepath.Join(*hd.sstate.PublicDir, mediaDir), mediaURL)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	hd.tmpl.URLPath = "/media/"
+	err = util.ExecuteTemplate(w, r, "media.html", hd.sstate.Tmpl, &hd.tmpl)
+	if err != nil {
+		util.LogHTTPError(w, err)
+		return
+	}
+}
+
+func mediaUploadHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.media.upload")))
+		return
+	}
+
+	// Leave some room for the multipart headers and other form fields
+	r.Body = http.MaxBytesReader(w, r.Body, *hd.sstate.UploadMax+1<<20)
+
+	file, _, err := r.FormFile("media-file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = media.ErrSize
+		}
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+	defer file.Close()
+
+	_, err = media.Save(filepath.Join(*hd.sstate.PublicDir, mediaDir), mediaURL, file, *hd.sstate.UploadMax)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	http.Redirect(w, r, mediaReturnPath(r), http.StatusSeeOther)
+}
+
+func mediaDeleteHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.media.delete")))
+		return
+	}
+
+	err = media.Delete(filepath.Join(*hd.sstate.PublicDir, mediaDir), r.URL.Path[len("/media/delete/"):])
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	http.Redirect(w, r, mediaReturnPath(r), http.StatusSeeOther)
+}
+
+// The upload form in the editor wants to go back to the editor
+func mediaReturnPath(r *http.Request) string {
+	return localPath(r.PostFormValue("media-return"), "/media/")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"dtla/internal/database"
+	"dtla/internal/i18n"
+	"dtla/internal/migrate"
+	"dtla/internal/store"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dtla migrate <up|down|status> [opcijas]
+func migrateCmd(args []string) error {
+	var err error
+
+	fset := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dbName := fset.String("db", "db", i18n.T(cliLang, "flag.db"))
+	steps := fset.Int("n", 1, i18n.T(cliLang, "flag.migrate.n"))
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), i18n.T(cliLang, "usage.migrate", filepath.Base(os.Args[0])))
+		fset.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		fset.Usage()
+		os.Exit(2)
+	}
+	action := args[0]
+	fset.Parse(args[1:])
+
+	// Opening would create a missing file, only up may start a new database
+	if action != "up" {
+		_, err = os.Stat(*dbName)
+		if err != nil {
+			return err
+		}
+	}
+	db, err := database.Open(*dbName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch action {
+	case "up":
+		applied, err := migrate.Up(db)
+		for _, m := range applied {
+			fmt.Printf("Pielietota %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Datubāze jau ir atjaunināta")
+		}
+		return bootstrapAdmin(context.Background(), store.NewSQLiteUsers(db))
+
+	case "down":
+		reverted, err := migrate.Down(db, *steps)
+		for _, m := range reverted {
+			fmt.Printf("Atcelta %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	case "status":
+		states, err := migrate.Status(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			if s.Applied.IsZero() {
+				fmt.Printf("%04d_%-20s nav pielietota\n", s.Version, s.Name)
+			} else {
+				fmt.Printf("%04d_%-20s pielietota %s\n", s.Version, s.Name, s.Applied.Format("2006-01-02 15:04:05"))
+			}
+		}
+		return nil
+	}
+
+	fset.Usage()
+	return fmt.Errorf("Nezināma darbība '%s'", action)
+}
+
+// Without any users nobody could log in to add them, so an empty database
+// gets an admin account. The name and password can be given with the
+// DTLA_ADMIN_USER and DTLA_ADMIN_PSWD environment variables, otherwise
+// the password is generated and printed once.
+func bootstrapAdmin(ctx context.Context, users store.UserStore) error {
+	var err error
+
+	count, err := users.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	user := os.Getenv("DTLA_ADMIN_USER")
+	if user == "" {
+		user = "admin"
+	}
+
+	pswd, given := os.LookupEnv("DTLA_ADMIN_PSWD")
+	if given && pswd == "" {
+		return errors.New("DTLA_ADMIN_PSWD nedrīkst būt tukšs")
+	}
+	if !given {
+		random := make([]byte, 12)
+		_, err = rand.Read(random)
+		if err != nil {
+			return err
+		}
+		pswd = base64.RawURLEncoding.EncodeToString(random)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pswd), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	_, err = users.Create(ctx, user, hash)
+	if err != nil {
+		return err
+	}
+
+	if given {
+		fmt.Printf("Izveidots lietotājs '%s'\n", user)
+	} else {
+		fmt.Printf("Izveidots lietotājs '%s' ar paroli '%s', tā netiks parādīta vēlreiz\n", user, pswd)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"dtla/internal/i18n"
+	"dtla/internal/util"
+	"flag"
+	"fmt"
+)
+
+// Compressed copies of the public files, served instead of compressing them
+// on every request. Run before `go build` they are embedded too.
+func precompressCmd(args []string) error {
+	var err error
+
+	fs := flag.NewFlagSet("precompress", flag.ExitOnError)
+	dir := fs.String("dir", "public", i18n.T(cliLang, "flag.precompress.dir"))
+	minSize := fs.Int64("min", 1024, i18n.T(cliLang, "flag.precompress.min"))
+	fs.Parse(args)
+
+	count, err := util.Precompress(*dir, *minSize)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Saspiesti %d faili direktorijā '%s'\n", count, *dir)
+	return nil
+}
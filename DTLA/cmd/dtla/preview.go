@@ -0,0 +1,48 @@
+package main
+
+import (
+	"dtla/internal/apperr"
+	"dtla/internal/i18n"
+	"dtla/internal/post"
+	"dtla/internal/util"
+	"net/http"
+)
+
+// Render the post from the edit or new post form like viewHandler() would
+// without saving it. The forms submit here into an iframe next to the editor.
+//
+// The page shows whatever HTML and JavaScript was submitted, so other sites
+// can't submit to it and it's sandboxed into its own origin, where its
+// scripts can't use the editor's session.
+func previewHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk || !util.SameOrigin(r) {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.preview")))
+		return
+	}
+
+	var page post.Page
+	err = page.LoadNewForm(r)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	data := viewData{
+		Page:        &page,
+		ContentLang: post.DefaultLang,
+		Preview:     true,
+	}
+	data.TOC = renderBody(&page, hd.tmpl.Lang, hd.sstate.Public)
+
+	hd.tmpl.Data = &data
+	hd.tmpl.URLPath = "/edit/"
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", "sandbox allow-scripts")
+	err = util.ExecuteTemplateHTML(w, r, "view.html", hd.sstate.Tmpl, &hd.tmpl)
+	if err != nil {
+		util.LogHTTPError(w, err)
+		return
+	}
+}
@@ -3,11 +3,17 @@ package main
 import (
 	"context"
 	"database/sql"
+	"dtla"
+	"dtla/internal/database"
+	"dtla/internal/editlock"
+	"dtla/internal/i18n"
+	"dtla/internal/store"
 	"dtla/internal/util"
 	"flag"
 	"fmt"
-	"html/template"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,6 +21,12 @@ import (
 	"time"
 )
 
+// Pages rendered with text/template, they escape what they show with {{html}} themselves
+var textPages []string = []string{"view.html"}
+
+// How often templates are checked for changes with -dev
+const templateWatchInterval = time.Second
+
 type ServerState struct {
 	srv       http.Server
 	mux       *http.ServeMux
@@ -25,10 +37,48 @@ type ServerState struct {
 	TLSPKey   *string
 	DBName    *string
 	DB        *sql.DB
+	Posts     store.PostStore
+	Users     store.UserStore
 	PublicDir *string
 	TmplDir   *string
-	Tmpl      *template.Template
+	// The embedded public directory with PublicDir on top
+	Public    fs.FS
+	Tmpl      *util.Templates
 	Verbose   *bool
+	UploadMax *int64
+	// Smallest response that is compressed, 0 turns compression off
+	CompressMin *int
+
+	// How long posts stay in the trash, 0 keeps them until purged by hand
+	TrashRetention *time.Duration
+
+	// Scheduled backups, none when BackupDir is empty
+	BackupDir   *string
+	BackupEvery *time.Duration
+	BackupKeep  *int
+
+	// Reload templates when they change
+	Dev *bool
+
+	// Apply pending migrations at startup, otherwise refuse to start with them
+	Migrate *bool
+
+	// Keep posts and users in memory instead of the database file, for demos
+	Mem *bool
+
+	// Per query limit and how long to wait for a locked database
+	QueryTimeout *time.Duration
+	BusyTimeout  *time.Duration
+
+	// Cancelled on shutdown, stops the queries of requests that didn't finish in time
+	// and the background jobs
+	ctx  context.Context
+	stop context.CancelFunc
+	// Closed when Shutdown() is done
+	stopped chan struct{}
+
+	// Posts open in the editor
+	EditLocks *editlock.Locks
 }
 
 func (s *ServerState) Init() error {
@@ -36,19 +86,35 @@ func (s *ServerState) Init() error {
 
 	// filepath.Clean() twice, first for help messages and second for flag value changes
 	*s = ServerState{
-		HttpIP:    flag.String("host", "127.0.0.1", "IP adrese uz kuras klausīties HTTP vaicājumus"),
-		HttpPort:  flag.String("port", "30000", "Ports uz kura klausīties HTTP vaicājumus"),
-		TLS:       flag.Bool("tls", true, "Vai klausīties izmantojot TLS"),
-		TLSCert:   flag.String("cert", "cert", "TLS sertifikāts"),
-		TLSPKey:   flag.String("key", "pkey", "TLS privātā atslēga"),
-		DBName:    flag.String("db", filepath.Clean("db"), "Datubāzes fails"),
-		PublicDir: flag.String("public", filepath.Clean("public"), "Publisko failu direktorija/folderis ar HTML, CSS, JavaScript, utt."),
-		TmplDir:   flag.String("tmpl", filepath.Clean("public/tmpl"), "Veidņu direktorija/folderis ar veidnēm, ko izmanto lai ģenerētu HTML saturu"),
-		Verbose:   flag.Bool("v", false, "Vairāk info"),
+		HttpIP:         flag.String("host", "127.0.0.1", i18n.T(cliLang, "flag.host")),
+		HttpPort:       flag.String("port", "30000", i18n.T(cliLang, "flag.port")),
+		TLS:            flag.Bool("tls", true, i18n.T(cliLang, "flag.tls")),
+		TLSCert:        flag.String("cert", "cert", i18n.T(cliLang, "flag.cert")),
+		TLSPKey:        flag.String("key", "pkey", i18n.T(cliLang, "flag.key")),
+		DBName:         flag.String("db", filepath.Clean("db"), i18n.T(cliLang, "flag.db")),
+		PublicDir:      flag.String("public", filepath.Clean("public"), i18n.T(cliLang, "flag.public")),
+		TmplDir:        flag.String("tmpl", filepath.Clean("public/tmpl"), i18n.T(cliLang, "flag.tmpl")),
+		Verbose:        flag.Bool("v", false, i18n.T(cliLang, "flag.v")),
+		Dev:            flag.Bool("dev", false, i18n.T(cliLang, "flag.dev")),
+		UploadMax:      flag.Int64("upload-max", 10<<20, i18n.T(cliLang, "flag.upload-max")),
+		CompressMin:    flag.Int("compress-min", 1024, i18n.T(cliLang, "flag.compress-min")),
+		Migrate:        flag.Bool("migrate", true, i18n.T(cliLang, "flag.migrate")),
+		Mem:            flag.Bool("mem", false, i18n.T(cliLang, "flag.mem")),
+		BackupDir:      flag.String("backup-dir", "", i18n.T(cliLang, "flag.backup-dir")),
+		BackupEvery:    flag.Duration("backup-every", 24*time.Hour, i18n.T(cliLang, "flag.backup-every")),
+		BackupKeep:     flag.Int("backup-keep", 7, i18n.T(cliLang, "flag.backup-keep")),
+		QueryTimeout:   flag.Duration("query-timeout", 5*time.Second, i18n.T(cliLang, "flag.query-timeout")),
+		BusyTimeout:    flag.Duration("busy-timeout", 5*time.Second, i18n.T(cliLang, "flag.busy-timeout")),
+		TrashRetention: flag.Duration("trash-retention", 30*24*time.Hour, i18n.T(cliLang, "flag.trash-retention")),
+	}
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), i18n.T(cliLang, "usage.server", filepath.Base(os.Args[0])))
+		flag.PrintDefaults()
+		commandsUsage()
 	}
 	flag.Parse()
 
-	// Absolute because of os.Chdir() later
+	// Absolute so messages show where files are looked for
 	// Abs() calls Clean()
 	*s.TLSCert, err = filepath.Abs(*s.TLSCert)
 	if err != nil {
@@ -65,36 +131,70 @@ func (s *ServerState) Init() error {
 		util.LogFatal(err.Error())
 	}
 
-	*s.PublicDir, err = filepath.Abs(*s.PublicDir)
-	if err != nil {
-		util.LogFatal(err.Error())
+	if *s.BackupDir != "" {
+		*s.BackupDir, err = filepath.Abs(*s.BackupDir)
+		if err != nil {
+			util.LogFatal(err.Error())
+		}
 	}
 
-	*s.TmplDir, err = filepath.Abs(*s.TmplDir)
+	*s.PublicDir, err = filepath.Abs(*s.PublicDir)
 	if err != nil {
 		util.LogFatal(err.Error())
 	}
 
-	err = os.Chdir(*s.PublicDir)
+	*s.TmplDir, err = filepath.Abs(*s.TmplDir)
 	if err != nil {
 		util.LogFatal(err.Error())
 	}
 
+	s.ctx, s.stop = context.WithCancel(context.Background())
+	s.stopped = make(chan struct{})
 	s.mux = http.NewServeMux()
 	s.srv = http.Server{
 		Addr:    *s.HttpIP + ":" + *s.HttpPort,
-		Handler: s.mux,
+		Handler: util.CompressHandler(langPrefixHandler(s.mux), *s.CompressMin),
+		// Request contexts are derived from it
+		BaseContext: func(net.Listener) context.Context {
+			return s.ctx
+		},
 	}
 
-	s.Tmpl, err = template.ParseGlob(*s.TmplDir + "/*.tmpl.html")
+	var partials fs.FS
+	s.Public, partials = publicFS(*s.PublicDir, *s.TmplDir)
+	s.Tmpl, err = util.NewTemplates(s.Public, partials, textPages, i18n.Funcs())
 	if err != nil {
-		util.LogError(err.Error())
+		return fmt.Errorf("Kļūda veidnēs: %w", err)
 	}
 	if *s.Verbose {
-		fmt.Println((*s).Tmpl.DefinedTemplates())
+		fmt.Println(s.Tmpl.Names())
 	}
 
-	s.DB, err = sql.Open("sqlite", *s.DBName)
+	s.EditLocks = editlock.New(editLockTTL)
+
+	database.Timeout = *s.QueryTimeout
+	database.BusyTimeout = *s.BusyTimeout
+	database.Verbose = *s.Verbose
+
+	if *s.Mem {
+		// Comments, translations and drafts still need a database, its posts
+		// table stays empty so deleting a post doesn't cascade to them
+		s.DB, err = openDB(":memory:", true)
+		if err != nil {
+			return err
+		}
+		s.Posts = store.NewMemPosts()
+		s.Users = store.NewMemUsers()
+	} else {
+		s.DB, err = openDB(*s.DBName, *s.Migrate)
+		if err != nil {
+			return err
+		}
+		s.Posts = store.NewSQLitePosts(s.DB)
+		s.Users = store.NewSQLiteUsers(s.DB)
+	}
+
+	err = bootstrapAdmin(s.ctx, s.Users)
 	if err != nil {
 		return err
 	}
@@ -102,6 +202,30 @@ func (s *ServerState) Init() error {
 	return nil
 }
 
+// The public directory built into the binary with the files from publicDir and
+// tmplDir on top, which don't have to exist. Returns the file system for pages
+// and assets and the one for the shared *.tmpl.html templates.
+func publicFS(publicDir string, tmplDir string) (fs.FS, fs.FS) {
+	embedded := dtla.Public()
+	embeddedTmpl, err := fs.Sub(embedded, "tmpl")
+	if err != nil {
+		panic(err)
+	}
+
+	public := util.OverlayFS{os.DirFS(publicDir), embedded}
+	partials := util.OverlayFS{os.DirFS(tmplDir), embeddedTmpl}
+	return public, partials
+}
+
+// Absolute URL of the server root used for links that leave the site, like feeds
+func (s *ServerState) BaseURL() string {
+	scheme := "http"
+	if *s.TLS {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(*s.HttpIP, *s.HttpPort)
+}
+
 func ListenShutdown(sstate *ServerState) {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt)
@@ -111,16 +235,11 @@ func ListenShutdown(sstate *ServerState) {
 	Shutdown(sstate, true, true)
 }
 
+// The HTTP server is shut down first so requests still running can finish
+// with the database, the ones that don't in time have their queries cancelled
 func Shutdown(sstate *ServerState, shutdownHTTP bool, shutdownDB bool) {
 	var err error
 
-	if shutdownDB {
-		err = sstate.DB.Close()
-		if err != nil {
-			log.Printf("Database closed with error: %s\n", err.Error())
-		}
-	}
-
 	if shutdownHTTP {
 		ctx, cancel := context.WithTimeout(context.Background(), 3000*time.Millisecond)
 		defer cancel()
@@ -129,4 +248,15 @@ func Shutdown(sstate *ServerState, shutdownHTTP bool, shutdownDB bool) {
 			log.Printf("HTTP Server closed with error: %s\n", err.Error())
 		}
 	}
+
+	sstate.stop()
+
+	if shutdownDB {
+		err = sstate.DB.Close()
+		if err != nil {
+			log.Printf("Database closed with error: %s\n", err.Error())
+		}
+	}
+
+	close(sstate.stopped)
 }
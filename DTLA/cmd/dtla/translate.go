@@ -0,0 +1,122 @@
+package main
+
+import (
+	"database/sql"
+	"dtla/internal/apperr"
+	"dtla/internal/i18n"
+	"dtla/internal/post"
+	"dtla/internal/util"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// The post in post.DefaultLang next to the form for its translation
+type translateData struct {
+	Source      *post.Page        `json:"source"`
+	Translation *post.Translation `json:"translation"`
+	Outdated    bool              `json:"outdated"`
+	Error       string            `json:"error,omitempty"`
+}
+
+func translateHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.translate")))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len("/translate/"):])
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	lang := r.URL.Query().Get("lang")
+	if lang == post.DefaultLang || !post.ValidLang(lang) {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, post.ErrLang)
+		return
+	}
+
+	var data translateData
+	data.Source, err = hd.sstate.Posts.Get(r.Context(), id)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	data.Translation, err = post.GetTranslation(r.Context(), hd.sstate.DB, id, lang)
+	if errors.Is(err, sql.ErrNoRows) {
+		data.Translation = &post.Translation{PostID: id, Lang: lang}
+	} else if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+	data.Outdated = data.Translation.SourceVersion != 0 && data.Translation.SourceVersion < data.Source.Version
+
+	executeTranslate(w, r, hd, &data)
+}
+
+func translatePostHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.translate")))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len("/translate/"):])
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	var data translateData
+	data.Source, err = hd.sstate.Posts.Get(r.Context(), id)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	data.Translation = &post.Translation{
+		PostID: id,
+		Lang:   r.PostFormValue("translation-lang"),
+		Title:  r.PostFormValue("translation-title"),
+		Desc:   r.PostFormValue("translation-desc"),
+		Body:   r.PostFormValue("translation-body"),
+	}
+
+	// The version the translator saw, if the post was saved in the meantime
+	// the translation is marked as outdated right away
+	data.Translation.SourceVersion, err = strconv.Atoi(r.PostFormValue("translation-source-version"))
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	err = checkShortcodes(data.Translation.Body, hd.sstate.Public)
+	if err == nil {
+		err = data.Translation.Save(r.Context(), hd.sstate.DB)
+	}
+	if err != nil {
+		data.Error = util.ErrorMessage(err, hd.tmpl.Lang)
+		util.WriteTemplateHeader(w, r, http.StatusBadRequest)
+		executeTranslate(w, r, hd, &data)
+		return
+	}
+
+	http.Redirect(w, r, "/"+data.Translation.Lang+"/view/"+strconv.Itoa(id), http.StatusSeeOther)
+}
+
+func executeTranslate(w http.ResponseWriter, r *http.Request, hd *handlerData, data *translateData) {
+	var err error
+
+	hd.tmpl.Data = data
+	hd.tmpl.URLPath = "/edit/"
+	err = util.ExecuteTemplate(w, r, "translate.html", hd.sstate.Tmpl, &hd.tmpl)
+	if err != nil {
+		util.LogError(err.Error())
+		return
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"dtla/internal/apperr"
+	"dtla/internal/i18n"
+	"dtla/internal/util"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func trashHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.trash")))
+		return
+	}
+
+	hd.tmpl.Data, err = hd.sstate.Posts.GetTrash(r.Context())
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	hd.tmpl.URLPath = "/view/"
+	err = util.ExecuteTemplate(w, r, "trash.html", hd.sstate.Tmpl, &hd.tmpl)
+	if err != nil {
+		util.LogHTTPError(w, err)
+		return
+	}
+}
+
+func trashRestoreHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.restore")))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len("/trash/restore/"):])
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	err = hd.sstate.Posts.Restore(r.Context(), id)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	http.Redirect(w, r, "/view/"+strconv.Itoa(id), http.StatusSeeOther)
+}
+
+func trashPurgeHandler(w http.ResponseWriter, r *http.Request, hd *handlerData) {
+	var err error
+
+	if hd.tmpl.Auth.Status != util.ASOk {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, apperr.New(apperr.Forbidden, i18n.T(hd.tmpl.Lang, "err.delete")))
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Path[len("/trash/purge/"):])
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	err = hd.sstate.Posts.Purge(r.Context(), id)
+	if err != nil {
+		util.ExecuteTemplateError(w, r, hd.sstate.Tmpl, &hd.tmpl, err)
+		return
+	}
+
+	http.Redirect(w, r, "/trash/", http.StatusSeeOther)
+}
+
+// Periodically delete posts that have been in the trash longer than -trash-retention
+func PurgeTrash(sstate *ServerState) {
+	if *sstate.TrashRetention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		n, err := sstate.Posts.PurgeOlder(sstate.ctx, time.Now().Add(-*sstate.TrashRetention))
+		if err != nil {
+			util.LogError(err.Error())
+		} else if n > 0 && *sstate.Verbose {
+			util.LogInfof("No miskastes izdzēsti %d ieteikumi\n", n)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-sstate.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package dtla
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// The public directory built into the binary, so it runs without one next to it.
+// Files in -public and -tmpl on disk are used instead of these when they exist.
+//
+//go:embed public
+var public embed.FS
+
+// The embedded public directory with paths relative to it, like "css/main.css"
+func Public() fs.FS {
+	sub, err := fs.Sub(public, "public")
+	if err != nil {
+		// Only fails for an invalid directory name
+		panic(err)
+	}
+	return sub
+}
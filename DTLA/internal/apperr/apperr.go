@@ -0,0 +1,117 @@
+package apperr
+
+import (
+	"database/sql"
+	"dtla/internal/i18n"
+	"errors"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Errors that know what went wrong from the visitor's point of view, which
+// decides the HTTP status code of the error page. Errors made with New() are
+// written for visitors and shown to them, the ones made with Wrap() carry
+// details that only go to the log and a generic message is shown instead.
+// Errors of packages that don't know the visitor's language are made with
+// NewT() and their message is looked up in the catalog where it's shown.
+
+type Kind int
+
+const (
+	Internal Kind = iota
+	NotFound
+	Forbidden
+	BadRequest
+	Conflict
+)
+
+type Error struct {
+	Kind Kind
+	// Shown to visitors
+	Msg string
+	// Catalog key and arguments of Msg, which is in i18n.Fallback
+	Key  string
+	Args []any
+	// Only logged
+	Err error
+}
+
+func New(kind Kind, msg string) *Error {
+	return &Error{Kind: kind, Msg: msg}
+}
+
+func NewT(kind Kind, key string, args ...any) *Error {
+	return &Error{Kind: kind, Msg: i18n.T(i18n.Fallback, key, args...), Key: key, Args: args}
+}
+
+func Wrap(kind Kind, err error) *Error {
+	return &Error{Kind: kind, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Msg
+	}
+	if e.Msg == "" {
+		return e.Err.Error()
+	}
+	return e.Msg + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (k Kind) Status() int {
+	switch k {
+	case NotFound:
+		return http.StatusNotFound
+	case Forbidden:
+		return http.StatusForbidden
+	case BadRequest:
+		return http.StatusBadRequest
+	case Conflict:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// The kind of the first *Error in err's chain. Errors that aren't one but
+// have an obvious kind, like sql.ErrNoRows for a missing post or a path ID
+// that isn't a number, get it too, everything else is Internal.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+
+	var numErr *strconv.NumError
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, fs.ErrNotExist):
+		return NotFound
+	case errors.As(err, &numErr):
+		return BadRequest
+	}
+
+	return Internal
+}
+
+// What can be shown to visitors about err in lang, empty if only the generic
+// message of its kind can. Context added by wrapping an error from New() is kept.
+func Message(err error, lang string) string {
+	var e *Error
+	if !errors.As(err, &e) || e.Msg == "" {
+		return ""
+	}
+
+	msg := e.Msg
+	if e.Key != "" {
+		msg = i18n.T(lang, e.Key, e.Args...)
+	}
+	if e.Err != nil {
+		return msg
+	}
+	return strings.ReplaceAll(err.Error(), e.Msg, msg)
+}
@@ -0,0 +1,276 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"dtla/internal/migrate"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backups are consistent copies of the database made with VACUUM INTO while
+// the server keeps running. Each backup dtla-YYYYMMDD-HHMMSS.db has a
+// checksum file next to it in the format of sha256sum, so `sha256sum -c` works too.
+
+var ErrChecksum error = errors.New("Rezerves kopijas kontrolsumma nesakrīt")
+var ErrNoChecksum error = errors.New("Rezerves kopijai nav kontrolsummas faila")
+
+var nameRe *regexp.Regexp = regexp.MustCompile(`^dtla-[0-9]{8}-[0-9]{6}\.db$`)
+
+const timeFormat = "20060102-150405"
+
+func ChecksumPath(path string) string {
+	return path + ".sha256"
+}
+
+// Write a backup of db into dir and remove the oldest ones so only `keep`
+// are left, 0 keeps all. Returns the path of the new backup.
+func Create(ctx context.Context, db *sql.DB, dir string, keep int) (string, error) {
+	var err error
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	name := "dtla-" + time.Now().Format(timeFormat) + ".db"
+	path := filepath.Join(dir, name)
+
+	// Written under another name first so a failed backup
+	// isn't mistaken for a complete one or rotated in
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	_, err = db.ExecContext(ctx, "VACUUM INTO ?", tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	sum, err := fileChecksum(tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	err = os.WriteFile(ChecksumPath(path), []byte(sum+"  "+name+"\n"), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	err = rotate(dir, keep)
+	if err != nil {
+		return path, err
+	}
+
+	return path, nil
+}
+
+// Backups in dir, oldest first
+func List(dir string) ([]string, error) {
+	var err error
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && nameRe.MatchString(entry.Name()) {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	// The time in the name sorts the same as a string
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+func rotate(dir string, keep int) error {
+	var err error
+
+	if keep <= 0 {
+		return nil
+	}
+
+	paths, err := List(dir)
+	if err != nil {
+		return err
+	}
+
+	for len(paths) > keep {
+		err = os.Remove(paths[0])
+		if err != nil {
+			return err
+		}
+		os.Remove(ChecksumPath(paths[0]))
+		paths = paths[1:]
+	}
+
+	return nil
+}
+
+// Compare the file against its checksum file
+func VerifyChecksum(path string) error {
+	var err error
+
+	data, err := os.ReadFile(ChecksumPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoChecksum
+	}
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return ErrChecksum
+	}
+
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	if sum != fields[0] {
+		return ErrChecksum
+	}
+
+	return nil
+}
+
+// Check that the file is an intact database with a schema this version of
+// the program knows. Only opens a copy so it can be called on the backup itself.
+func Validate(path string) error {
+	var err error
+
+	tmp, err := os.CreateTemp("", "dtla-validate-*.db")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	err = copyFile(path, tmp.Name())
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	err = db.QueryRow("PRAGMA integrity_check").Scan(&result)
+	if err != nil {
+		return fmt.Errorf("Fails nav SQLite datubāze: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("Datubāze ir bojāta: %s", result)
+	}
+
+	return migrate.Verify(db)
+}
+
+// Replace the database file `dbName` with the backup, keeping the old file as
+// dbName.pre-restore. The server must not be using the database meanwhile.
+func Restore(path string, dbName string) error {
+	var err error
+
+	err = Validate(path)
+	if err != nil {
+		return err
+	}
+
+	// Copied next to the database first so the swap is a rename on the same file system
+	tmp := dbName + ".restore"
+	err = copyFile(path, tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	_, err = os.Stat(dbName)
+	if err == nil {
+		err = os.Rename(dbName, dbName+".pre-restore")
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		os.Remove(tmp)
+		return err
+	}
+
+	// The journal and WAL of the old database go with it, after a crash the WAL
+	// still has committed changes and left in place it would be applied to the restored one
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		os.Remove(dbName + ".pre-restore" + suffix)
+		os.Rename(dbName+suffix, dbName+".pre-restore"+suffix)
+	}
+
+	return os.Rename(tmp, dbName)
+}
+
+func fileChecksum(path string) (string, error) {
+	var err error
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func copyFile(from string, to string) error {
+	var err error
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+
+	err = dst.Sync()
+	if err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
@@ -0,0 +1,130 @@
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"dtla/internal/database"
+	"time"
+)
+
+type Comment struct {
+	ID     int `json:"id"`
+	PostID int `json:"postId"`
+
+	// Name given by an anonymous reader or the user name if logged in
+	Author string `json:"author"`
+	// 0 for anonymous comments
+	UserID int `json:"userId"`
+
+	Body    string    `json:"body"`
+	Created time.Time `json:"created"`
+	Status  int       `json:"status"`
+
+	// Kept for rate limiting anonymous comments
+	IP string `json:"-"`
+}
+
+const (
+	StatusPending = iota
+	StatusApproved
+	StatusRejected
+)
+
+func (c *Comment) Insert(ctx context.Context, db *sql.DB) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	c.Created = time.Unix(time.Now().Unix(), 0)
+	res, err := db.ExecContext(ctx, "INSERT INTO comments (post_id, author, user_id, body, created, status, ip) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		c.PostID, c.Author, c.UserID, c.Body, c.Created.Unix(), c.Status, c.IP)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	c.ID = int(id)
+
+	return nil
+}
+
+// Approved comments of a post, oldest first
+func GetApproved(ctx context.Context, db *sql.DB, postID int) ([]*Comment, error) {
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	return query(ctx, db, "WHERE post_id IS ? AND status IS ? ORDER BY created", postID, StatusApproved)
+}
+
+// The moderation queue, oldest first
+func GetPending(ctx context.Context, db *sql.DB) ([]*Comment, error) {
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	return query(ctx, db, "WHERE status IS ? ORDER BY created", StatusPending)
+}
+
+func CountPending(ctx context.Context, db *sql.DB) (int, error) {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	var n int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM comments WHERE status IS ?", StatusPending).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func SetStatus(ctx context.Context, db *sql.DB, id int, status int) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	res, err := db.ExecContext(ctx, "UPDATE comments SET status = ? WHERE id IS ?", status, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func query(ctx context.Context, db *sql.DB, where string, args ...any) ([]*Comment, error) {
+	var err error
+
+	rows, err := db.QueryContext(ctx, "SELECT id, post_id, author, user_id, body, created, status, ip FROM comments "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*Comment
+	for rows.Next() {
+		var c *Comment = new(Comment)
+		var created int64
+		err = rows.Scan(&c.ID, &c.PostID, &c.Author, &c.UserID, &c.Body, &created, &c.Status, &c.IP)
+		if err != nil {
+			return nil, err
+		}
+		c.Created = time.Unix(created, 0)
+		comments = append(comments, c)
+	}
+
+	return comments, rows.Err()
+}
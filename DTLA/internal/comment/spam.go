@@ -0,0 +1,107 @@
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"dtla/internal/database"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Heuristics for anonymous comments instead of a captcha.
+// ErrSpam is for comments that are almost certainly from a bot, the caller
+// should pretend they were accepted. The other errors are shown to the reader.
+
+const (
+	MaxAuthorLen = 50
+	MaxBodyLen   = 4000
+
+	// A person takes longer than this to write something
+	minFormTime = 3 * time.Second
+	// Forms older than this were most likely scraped and replayed
+	maxFormTime = 24 * time.Hour
+
+	MaxLinks = 2
+
+	rateWindow = 10 * time.Minute
+	rateLimit  = 3
+)
+
+var ErrSpam error = errors.New("Komentārs izskatās pēc surogātpasta")
+var ErrEmpty error = errors.New("Komentārs nedrīkst būt tukšs")
+var ErrAuthor error = fmt.Errorf("Vārdam jābūt no 1 līdz %d simboliem", MaxAuthorLen)
+var ErrLong error = fmt.Errorf("Komentārs nedrīkst būt garāks par %d simboliem", MaxBodyLen)
+var ErrLinks error = fmt.Errorf("Komentārā var būt ne vairāk kā %d saites", MaxLinks)
+var ErrRate error = errors.New("Pārāk daudz komentāru, mēģini vēlāk")
+var ErrDuplicate error = errors.New("Šāds komentārs jau ir iesniegts")
+
+// Fields of the comment form used by the heuristics besides the comment itself
+type Form struct {
+	// Hidden field people don't see and bots fill out
+	Honeypot string
+	// When the form was rendered, from the hidden field
+	Rendered time.Time
+}
+
+// Checks for every comment, logged in or not
+func (c *Comment) Validate() error {
+	c.Body = strings.TrimSpace(c.Body)
+	c.Author = strings.TrimSpace(c.Author)
+
+	if c.Body == "" {
+		return ErrEmpty
+	}
+	if utf8.RuneCountInString(c.Body) > MaxBodyLen {
+		return ErrLong
+	}
+	if c.Author == "" || utf8.RuneCountInString(c.Author) > MaxAuthorLen {
+		return ErrAuthor
+	}
+
+	return nil
+}
+
+// Spam checks for anonymous comments
+func (c *Comment) CheckSpam(ctx context.Context, db *sql.DB, form Form) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	if form.Honeypot != "" {
+		return ErrSpam
+	}
+
+	age := time.Since(form.Rendered)
+	if age < minFormTime || age > maxFormTime {
+		return ErrSpam
+	}
+
+	body := strings.ToLower(c.Body)
+	if strings.Count(body, "http://")+strings.Count(body, "https://")+strings.Count(body, "www.") > MaxLinks {
+		return ErrLinks
+	}
+
+	var recent int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM comments WHERE ip IS ? AND created > ?", c.IP, time.Now().Add(-rateWindow).Unix()).Scan(&recent)
+	if err != nil {
+		return err
+	}
+	if recent >= rateLimit {
+		return ErrRate
+	}
+
+	var duplicate bool
+	err = db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM comments WHERE body IS ?)", c.Body).Scan(&duplicate)
+	if err != nil {
+		return err
+	}
+	if duplicate {
+		return ErrDuplicate
+	}
+
+	return nil
+}
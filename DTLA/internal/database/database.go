@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"dtla/internal/util"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Settings shared by everything that queries the database, set from flags at startup
+
+// Longest a single store call may take before its query is cancelled, 0 - no limit
+var Timeout time.Duration = 5 * time.Second
+
+// How long to wait for a lock held by another connection before failing with SQLITE_BUSY
+var BusyTimeout time.Duration = 5 * time.Second
+
+// Log how long every store call took
+var Verbose bool = false
+
+// Open the SQLite database with write-ahead logging, so readers don't block the
+// writer, and the busy timeout set on every connection
+func Open(name string) (*sql.DB, error) {
+	var err error
+
+	// Pragmas in the DSN are run by the driver on each new connection
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(wal)", name, BusyTimeout.Milliseconds())
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every connection to ":memory:" gets its own empty database
+	if name == ":memory:" {
+		db.SetMaxOpenConns(1)
+	}
+
+	// Opening is lazy, fail here on a bad file instead of on the first request
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Limit a store call to Timeout and time it. The returned function must be
+// called when the call is done, usually with defer, e.g.
+//
+//	ctx, done := database.Start(ctx)
+//	defer done()
+func Start(ctx context.Context) (context.Context, func()) {
+	cancel := func() {}
+	if Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, Timeout)
+	}
+
+	if !Verbose {
+		return ctx, cancel
+	}
+
+	start := time.Now()
+	name := "?"
+	pc, _, _, ok := runtime.Caller(1)
+	if ok {
+		// dtla/internal/post.GetPage -> post.GetPage
+		name = runtime.FuncForPC(pc).Name()
+		name = name[strings.LastIndex(name, "/")+1:]
+	}
+
+	return ctx, func() {
+		cancel()
+		util.LogInfof("%s %s\n", name, time.Since(start))
+	}
+}
@@ -0,0 +1,85 @@
+package editlock
+
+import (
+	"sync"
+	"time"
+)
+
+// Soft locks on posts being edited. A lock is kept alive by heartbeats from
+// the edit page and expires when they stop, for example when the tab is closed
+// without releasing it. Locks only live in memory, a restart releases all of them.
+
+type Lock struct {
+	PostID int    `json:"postId"`
+	UserID int    `json:"userId"`
+	User   string `json:"user"`
+
+	// When the editor opened the post
+	Since     time.Time `json:"since"`
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+type Locks struct {
+	mu    sync.Mutex
+	locks map[int]Lock
+	ttl   time.Duration
+}
+
+// Locks expire `ttl` after the last heartbeat
+func New(ttl time.Duration) *Locks {
+	return &Locks{
+		locks: make(map[int]Lock),
+		ttl:   ttl,
+	}
+}
+
+// Lock the post for the user unless someone else holds the lock.
+// Returns the lock that is in place and whether it's the user's.
+func (l *Locks) Acquire(postID int, userID int, user string) (Lock, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	lock, ok := l.locks[postID]
+	if ok && lock.UserID != userID && now.Sub(lock.Heartbeat) < l.ttl {
+		return lock, false
+	}
+
+	// Opening the post again in another tab keeps the original time
+	if !ok || lock.UserID != userID {
+		lock = Lock{PostID: postID, UserID: userID, User: user, Since: now}
+	}
+	lock.Heartbeat = now
+	l.locks[postID] = lock
+
+	return lock, true
+}
+
+// Take the lock from whoever holds it
+func (l *Locks) TakeOver(postID int, userID int, user string) Lock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	lock := Lock{PostID: postID, UserID: userID, User: user, Since: now, Heartbeat: now}
+	l.locks[postID] = lock
+
+	return lock
+}
+
+// Keep the user's lock alive. Returns the lock in place and false if the
+// lock was taken over by someone else who still holds it.
+func (l *Locks) Heartbeat(postID int, userID int, user string) (Lock, bool) {
+	return l.Acquire(postID, userID, user)
+}
+
+// Release the user's lock, a lock held by someone else is left alone
+func (l *Locks) Release(postID int, userID int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, ok := l.locks[postID]
+	if ok && lock.UserID == userID {
+		delete(l.locks, postID)
+	}
+}
@@ -0,0 +1,209 @@
+package i18n
+
+var en map[string]string = map[string]string{
+	// Server flags
+	"flag.host":            "IP address to listen for HTTP requests on",
+	"flag.port":            "Port to listen for HTTP requests on",
+	"flag.tls":             "Whether to listen using TLS",
+	"flag.cert":            "TLS certificate",
+	"flag.key":             "TLS private key",
+	"flag.db":              "Database file",
+	"flag.public":          "Directory of public files with HTML, CSS, JavaScript, etc., files in it replace the built in ones",
+	"flag.tmpl":            "Directory of templates used to generate HTML content, templates in it replace the built in ones",
+	"flag.v":               "More info",
+	"flag.dev":             "Development mode, templates are reloaded when they change",
+	"flag.upload-max":      "Largest file that can be uploaded, in bytes",
+	"flag.migrate":         "Apply database migrations when starting the server",
+	"flag.mem":             "Keep posts and users in memory instead of the database, they are lost when the server stops",
+	"flag.backup-dir":      "Directory to regularly save database backups in, empty - don't save them",
+	"flag.backup-every":    "How often to save a backup",
+	"flag.backup-keep":     "How many of the newest backups to keep, 0 - all",
+	"flag.query-timeout":   "How long a single database query may run, 0 - no limit",
+	"flag.busy-timeout":    "How long to wait if another connection has locked the database",
+	"flag.trash-retention": "How long deleted posts are kept in the trash, 0 - until they are deleted by hand",
+	"flag.compress-min":    "Smallest response to compress with brotli or gzip, in bytes, 0 - don't compress",
+
+	// Subcommand flags
+	"flag.backup.out":            "Directory to save backups in",
+	"flag.restore.db":            "Database file to replace",
+	"flag.restore.skip-checksum": "Restore even if the backup has no checksum file",
+	"flag.build.public":          "Directory of public files with HTML, CSS, JavaScript, etc.",
+	"flag.build.tmpl":            "Directory of templates used to generate HTML content",
+	"flag.build.out":             "Directory to save the static site in",
+	"flag.export.out":            "Directory to save posts in",
+	"flag.export.prune":          "Delete post files in the directory that weren't exported, e.g. of deleted or renamed posts",
+	"flag.import.in":             "Directory to load posts from",
+	"flag.import.prune":          "Move posts that aren't in the directory to the trash",
+	"flag.migrate.n":             "How many migrations to revert with 'down'",
+	"flag.precompress.dir":       "Directory to save compressed .br and .gz copies next to its files in",
+	"flag.precompress.min":       "Smallest file to compress, in bytes",
+
+	// Usage lines, %s is the program name
+	"usage.server":   "Usage: %s [options] | <command> [options]",
+	"usage.commands": "Commands (%s <command> --help):",
+	"usage.restore":  "Usage: %s restore [options] <backup>",
+	"usage.migrate":  "Usage: %s migrate <up|down|status> [options]",
+
+	// Errors shown by handlers
+	"err.edit":             "Not allowed to edit the post",
+	"err.new":              "Not allowed to create a new post",
+	"err.delete":           "Not allowed to delete the post",
+	"err.restore":          "Not allowed to restore the post",
+	"err.trash":            "Not allowed to view the trash",
+	"err.translate":        "Not allowed to translate the post",
+	"err.preview":          "Not allowed to preview the post",
+	"err.moderate":         "Not allowed to moderate comments",
+	"err.media.view":       "Not allowed to view files",
+	"err.media.upload":     "Not allowed to upload files",
+	"err.media.delete":     "Not allowed to delete files",
+	"err.windows":          "Not supported on a Windows server",
+	"err.login.empty":      "Username and password must not be empty",
+	"err.login.no-user":    "User doesn't exist",
+	"err.login.pswd":       "Wrong password",
+	"err.auth.session":     "Your session isn't valid, log in again",
+	"err.api.unauthorized": "You need to log in",
+	"err.api.content-type": "The request content must be application/json",
+	"err.post.not-found":   "The post doesn't exist",
+	"err.locked":           "%s has been editing the post since %s",
+
+	// Errors of the internal packages, looked up in the visitor's language where they're shown
+	"err.auth.expired":      "Your session has expired",
+	"err.post.title-empty":  "The title must not be empty",
+	"err.post.title-long":   "The title must not be longer than %d characters",
+	"err.post.desc-long":    "The description must not be longer than %d characters",
+	"err.post.body-long":    "The content must not be longer than %d bytes",
+	"err.post.conflict":     "Someone else has changed the post since you started editing it",
+	"err.post.json":         "Invalid JSON: %s",
+	"err.post.lang":         "Unsupported language",
+	"err.media.type":        "Unsupported file type",
+	"err.media.size":        "The file is too large",
+	"err.media.name":        "Invalid file name",
+	"err.shortcode":         "Error in shortcodes: %s",
+	"err.shortcode.missing": "File not found",
+	"err.shortcode.args":    "Wrong number of arguments",
+
+	// Shared templates
+	"site.title":     "Logical protection of computer systems and networks",
+	"nav.posts":      "Posts",
+	"nav.tools":      "Tools",
+	"nav.crypto":     "Cryptography",
+	"nav.sockets":    "Network connections",
+	"nav.media":      "Files",
+	"nav.comments":   "Comments",
+	"nav.logout":     "Log out",
+	"nav.login":      "Log in",
+	"footer.license": "License",
+
+	// Pages
+	"login.title":   "Log in",
+	"login.name":    "Username",
+	"login.pswd":    "Password",
+	"login.submit":  "Log in",
+	"login.ok":      "All ok",
+	"posts.title":   "Posts",
+	"posts.new":     "New",
+	"posts.trash":   "Trash",
+	"posts.edit":    "Edit",
+	"posts.delete":  "Delete",
+	"trash.title":   "Trash",
+	"trash.restore": "Restore",
+	"trash.purge":   "Delete permanently",
+	"trash.deleted": "Deleted by %s %s",
+	"trash.empty":   "The trash is empty",
+	"feed.atom":     "Posts (Atom)",
+	"feed.rss":      "Posts (RSS)",
+
+	// Post page, the view.html template and the rendered body
+	"view.translation.missing":  "not translated",
+	"view.translation.outdated": "translation outdated",
+	"view.translation.ok":       "translated",
+	"view.untranslated":         "This post has not been translated yet, it is shown in Latvian.",
+	"view.toc":                  "Contents",
+	"view.toc.anchor":           "Copy link to section",
+	"view.comments":             "Comments and questions",
+	"view.comments.none":        "No comments yet",
+	"view.comments.pending":     "Thank you! The comment will be visible once it's approved.",
+	"view.comments.ok":          "Comment added",
+	"view.comments.author":      "Name",
+	"view.comments.body":        "Comment or question",
+	"view.comments.submit":      "Comment",
+
+	// Editing pages
+	"editor.title":          "Title",
+	"editor.desc":           "Description",
+	"editor.tags":           "Tags, separated by commas",
+	"editor.body":           "Content",
+	"editor.preview":        "Preview",
+	"editor.preview.title":  "Preview",
+	"new.title":             "New post",
+	"new.from":              "Start from",
+	"new.empty":             "Empty",
+	"new.load":              "Load",
+	"new.duplicate":         "A post titled \"%s\" already exists.",
+	"new.duplicate.confirm": "Create anyway",
+	"new.submit":            "Create",
+	"edit.title":            "Editing %s",
+	"edit.draft.applied":    "The form was filled in from a draft saved at %s. Submit it to save it.",
+	"edit.draft.found":      "There is an unsaved draft from %s.",
+	"edit.draft.outdated":   "There is an unsaved draft from %s, the post has changed since then.",
+	"edit.draft.apply":      "Restore",
+	"edit.draft.discard":    "Discard",
+	"edit.submit":           "Submit",
+	"conflict.title":        "Conflict %s",
+	"conflict.msg":          "While you were editing, someone else saved this post (version %d, %s). Merge the changes below and save again.",
+	"conflict.mine":         "Your version",
+	"conflict.theirs":       "Current version",
+	"conflict.merged":       "Merged version",
+	"conflict.submit":       "Save merged",
+	"locked.msg":            "The post \"%s\" is being edited by %s since %s.",
+	"locked.warning":        "If you take over editing, %s will get a warning. If both of you save changes, the other one will have to merge them.",
+	"locked.takeover":       "Take over editing",
+	"delete.title":          "Delete %s",
+	"delete.msg":            "Really move the post \"%s\" to the trash?",
+	"translate.title":       "Translating %s",
+	"translate.outdated":    "The post has changed since it was translated (translated from version %d, current version %d)",
+	"translate.source":      "Original",
+	"translate.target":      "Translation (%s)",
+	"translate.submit":      "Save translation",
+	"form.cancel":           "Cancel",
+
+	// Media and moderation pages
+	"media.title":        "Files",
+	"media.upload":       "Upload",
+	"media.insert":       "Insert",
+	"media.delete":       "Delete",
+	"media.empty":        "No uploaded files",
+	"moderation.title":   "Comment moderation",
+	"moderation.on":      "on",
+	"moderation.approve": "Approve",
+	"moderation.reject":  "Reject",
+	"moderation.empty":   "No comments waiting for approval",
+
+	// Editor status messages, used by js/edit.js
+	"draft.saved":   "Draft saved",
+	"draft.failed":  "The draft couldn't be saved",
+	"draft.expired": "The session has expired, the draft isn't being saved. Log in in another tab.",
+	"lock.merge":    "Saving will need the changes to be merged.",
+
+	// Why a comment was rejected, by the key in the redirect back to the post
+	"comment.empty":     "The comment must not be empty",
+	"comment.author":    "The name must be 1 to %d characters long",
+	"comment.long":      "The comment must not be longer than %d characters",
+	"comment.links":     "The comment can have at most %d links",
+	"comment.rate":      "Too many comments, try again later",
+	"comment.duplicate": "This comment has already been submitted",
+
+	// Error pages by status code, the message is shown when the error has none for visitors
+	"error.400":     "Bad request",
+	"error.400.msg": "The request can't be completed, check the address or what was entered.",
+	"error.403":     "Not allowed",
+	"error.403.msg": "You need to log in to do that.",
+	"error.404":     "Not found",
+	"error.404.msg": "There is no such post or page, it may have been deleted.",
+	"error.409":     "Conflict",
+	"error.409.msg": "Someone else has changed it in the meantime, try again.",
+	"error.500":     "Server error",
+	"error.500.msg": "Something went wrong on the server, try again later.",
+	"error.posts":   "Go to posts",
+	"error.home":    "Go to the home page",
+}
@@ -0,0 +1,64 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Messages shown to users, looked up by key in the catalog of the language
+// they read the site in. A key missing from that catalog falls back to the one
+// in Fallback and then to the key itself, so a forgotten message shows up as
+// its key instead of an empty string.
+
+// Same as post.DefaultLang, every key must be in its catalog
+const Fallback = "lv"
+
+var catalogs map[string]map[string]string = map[string]map[string]string{
+	"lv": lv,
+	"en": en,
+}
+
+// The message for key in lang, formatted with fmt.Sprintf if args are given
+func T(lang string, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		msg, ok = catalogs[Fallback][key]
+	}
+	if !ok {
+		return key
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Template functions, used as {{T .Lang "nav.posts"}}
+func Funcs() map[string]any {
+	return map[string]any{
+		"T": T,
+	}
+}
+
+// Language for the command line from the locale environment variables,
+// e.g. LANG=en_US.UTF-8 is en, Fallback if there's no catalog for it
+func EnvLang() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+
+		lang, _, _ := strings.Cut(value, "_")
+		lang, _, _ = strings.Cut(lang, ".")
+		lang = strings.ToLower(lang)
+		if _, ok := catalogs[lang]; ok {
+			return lang
+		}
+		return Fallback
+	}
+
+	return Fallback
+}
@@ -0,0 +1,209 @@
+package i18n
+
+var lv map[string]string = map[string]string{
+	// Server flags
+	"flag.host":            "IP adrese uz kuras klausīties HTTP vaicājumus",
+	"flag.port":            "Ports uz kura klausīties HTTP vaicājumus",
+	"flag.tls":             "Vai klausīties izmantojot TLS",
+	"flag.cert":            "TLS sertifikāts",
+	"flag.key":             "TLS privātā atslēga",
+	"flag.db":              "Datubāzes fails",
+	"flag.public":          "Publisko failu direktorija/folderis ar HTML, CSS, JavaScript, utt., faili tajā aizstāj programmā iebūvētos",
+	"flag.tmpl":            "Veidņu direktorija/folderis ar veidnēm, ko izmanto lai ģenerētu HTML saturu, veidnes tajā aizstāj programmā iebūvētās",
+	"flag.v":               "Vairāk info",
+	"flag.dev":             "Izstrādes režīms, veidnes tiek pārlādētas, kad tās maina",
+	"flag.upload-max":      "Maksimālais augšupielādējamā faila izmērs baitos",
+	"flag.migrate":         "Pielietot datubāzes migrācijas palaižot serveri",
+	"flag.mem":             "Glabāt ieteikumus un lietotājus atmiņā, nevis datubāzē, tie tiek zaudēti apturot serveri",
+	"flag.backup-dir":      "Direktorija/folderis, kurā regulāri saglabāt datubāzes rezerves kopijas, tukšs - nesaglabāt",
+	"flag.backup-every":    "Cik bieži saglabāt rezerves kopiju",
+	"flag.backup-keep":     "Cik jaunākās rezerves kopijas paturēt, 0 - visas",
+	"flag.query-timeout":   "Cik ilgi drīkst izpildīties viens datubāzes vaicājums, 0 - bez ierobežojuma",
+	"flag.busy-timeout":    "Cik ilgi gaidīt, ja datubāzi ir aizslēdzis cits savienojums",
+	"flag.trash-retention": "Cik ilgi dzēsti ieteikumi tiek glabāti miskastē, 0 - līdz tos izdzēš manuāli",
+	"flag.compress-min":    "Mazākā atbilde baitos, ko saspiest ar brotli vai gzip, 0 - nesaspiest",
+
+	// Subcommand flags
+	"flag.backup.out":            "Direktorija/folderis, kurā saglabāt rezerves kopijas",
+	"flag.restore.db":            "Datubāzes fails, ko aizstāt",
+	"flag.restore.skip-checksum": "Atjaunot arī, ja rezerves kopijai nav kontrolsummas faila",
+	"flag.build.public":          "Publisko failu direktorija/folderis ar HTML, CSS, JavaScript, utt.",
+	"flag.build.tmpl":            "Veidņu direktorija/folderis ar veidnēm, ko izmanto lai ģenerētu HTML saturu",
+	"flag.build.out":             "Direktorija/folderis, kurā saglabāt statisko lapu",
+	"flag.export.out":            "Direktorija/folderis, kurā saglabāt ieteikumus",
+	"flag.export.prune":          "Dzēst direktorijā ieteikumu failus, kas netika eksportēti, piemēram, dzēstu vai pārdēvētu ieteikumu",
+	"flag.import.in":             "Direktorija/folderis, no kuras ielādēt ieteikumus",
+	"flag.import.prune":          "Pārvietot uz miskasti ieteikumus, kuru nav direktorijā",
+	"flag.migrate.n":             "Cik migrācijas atcelt ar 'down'",
+	"flag.precompress.dir":       "Direktorija/folderis, kuras failiem blakus saglabāt saspiestas .br un .gz kopijas",
+	"flag.precompress.min":       "Mazākais fails baitos, ko saspiest",
+
+	// Usage lines, %s is the program name
+	"usage.server":   "Lietošana: %s [opcijas] | <komanda> [opcijas]",
+	"usage.commands": "Komandas (%s <komanda> --help):",
+	"usage.restore":  "Lietošana: %s restore [opcijas] <rezerves kopija>",
+	"usage.migrate":  "Lietošana: %s migrate <up|down|status> [opcijas]",
+
+	// Errors shown by handlers
+	"err.edit":             "Nav atļauts rediģēt ieteikumu",
+	"err.new":              "Nav atļauts izveidot jaunu ieteikumu",
+	"err.delete":           "Nav atļauts dzēst ieteikumu",
+	"err.restore":          "Nav atļauts atjaunot ieteikumu",
+	"err.trash":            "Nav atļauts skatīt miskasti",
+	"err.translate":        "Nav atļauts tulkot ieteikumu",
+	"err.preview":          "Nav atļauts priekšskatīt ieteikumu",
+	"err.moderate":         "Nav atļauts moderēt komentārus",
+	"err.media.view":       "Nav atļauts skatīt failus",
+	"err.media.upload":     "Nav atļauts augšupielādēt failus",
+	"err.media.delete":     "Nav atļauts dzēst failus",
+	"err.windows":          "Nav atbalstīts uz Windows servera",
+	"err.login.empty":      "Lietotājvārds un parole nedrīkst būt neaizpildīti",
+	"err.login.no-user":    "Lietotājs neeksistē",
+	"err.login.pswd":       "Nepareiza parole",
+	"err.auth.session":     "Sesija nav derīga, ielogojies vēlreiz",
+	"err.api.unauthorized": "Nepieciešams ielogoties",
+	"err.api.content-type": "Pieprasījuma saturam jābūt application/json",
+	"err.post.not-found":   "Ieteikums neeksistē",
+	"err.locked":           "Ieteikumu rediģē %s kopš %s",
+
+	// Errors of the internal packages, looked up in the visitor's language where they're shown
+	"err.auth.expired":      "Sesija ir beigusies",
+	"err.post.title-empty":  "Virsraksts nedrīkst būt tukšs",
+	"err.post.title-long":   "Virsraksts nedrīkst būt garāks par %d simboliem",
+	"err.post.desc-long":    "Apraksts nedrīkst būt garāks par %d simboliem",
+	"err.post.body-long":    "Saturs nedrīkst būt garāks par %d baitiem",
+	"err.post.conflict":     "Ieteikumu kopš rediģēšanas sākuma ir mainījis kāds cits",
+	"err.post.json":         "Nederīgs JSON: %s",
+	"err.post.lang":         "Neatbalstīta valoda",
+	"err.media.type":        "Neatbalstīts faila tips",
+	"err.media.size":        "Fails ir pārāk liels",
+	"err.media.name":        "Nederīgs faila nosaukums",
+	"err.shortcode":         "Kļūda īskodos: %s",
+	"err.shortcode.missing": "Fails nav atrasts",
+	"err.shortcode.args":    "Nepareizs argumentu skaits",
+
+	// Shared templates
+	"site.title":     "Datorsistēmu un tīklu loģiskā aizsardzība",
+	"nav.posts":      "Ieteikumi",
+	"nav.tools":      "Rīki",
+	"nav.crypto":     "Kriptogrāfija",
+	"nav.sockets":    "Tīkla savienojumi",
+	"nav.media":      "Faili",
+	"nav.comments":   "Komentāri",
+	"nav.logout":     "Iziet",
+	"nav.login":      "Ieiet",
+	"footer.license": "Licence",
+
+	// Pages
+	"login.title":   "Ieiet",
+	"login.name":    "Lietotājvārds",
+	"login.pswd":    "Parole",
+	"login.submit":  "Ieiet",
+	"login.ok":      "Viss ok",
+	"posts.title":   "Ieteikumi",
+	"posts.new":     "Jauns",
+	"posts.trash":   "Miskaste",
+	"posts.edit":    "Rediģēt",
+	"posts.delete":  "Dzēst",
+	"trash.title":   "Miskaste",
+	"trash.restore": "Atjaunot",
+	"trash.purge":   "Dzēst neatgriezeniski",
+	"trash.deleted": "Dzēsa %s %s",
+	"trash.empty":   "Miskaste ir tukša",
+	"feed.atom":     "Ieteikumi (Atom)",
+	"feed.rss":      "Ieteikumi (RSS)",
+
+	// Post page, the view.html template and the rendered body
+	"view.translation.missing":  "nav tulkojuma",
+	"view.translation.outdated": "tulkojums novecojis",
+	"view.translation.ok":       "tulkots",
+	"view.untranslated":         "Šis ieteikums vēl nav iztulkots, tas ir parādīts latviešu valodā.",
+	"view.toc":                  "Saturs",
+	"view.toc.anchor":           "Kopēt saiti uz sadaļu",
+	"view.comments":             "Komentāri un jautājumi",
+	"view.comments.none":        "Vēl nav komentāru",
+	"view.comments.pending":     "Paldies! Komentārs būs redzams pēc tam, kad to apstiprinās.",
+	"view.comments.ok":          "Komentārs pievienots",
+	"view.comments.author":      "Vārds",
+	"view.comments.body":        "Komentārs vai jautājums",
+	"view.comments.submit":      "Komentēt",
+
+	// Editing pages
+	"editor.title":          "Virsraksts",
+	"editor.desc":           "Apraksts",
+	"editor.tags":           "Birkas, atdalītas ar komatu",
+	"editor.body":           "Saturs",
+	"editor.preview":        "Priekšskatīt",
+	"editor.preview.title":  "Priekšskatījums",
+	"new.title":             "Jauns ieteikums",
+	"new.from":              "Sākt no",
+	"new.empty":             "Tukšs",
+	"new.load":              "Ielādēt",
+	"new.duplicate":         "Ieteikums ar virsrakstu \"%s\" jau eksistē.",
+	"new.duplicate.confirm": "Tomēr izveidot",
+	"new.submit":            "Izveidot",
+	"edit.title":            "Rediģē %s",
+	"edit.draft.applied":    "Forma aizpildīta no melnraksta, kas saglabāts %s. Iesniedz, lai to saglabātu.",
+	"edit.draft.found":      "Ir nesaglabāts melnraksts no %s.",
+	"edit.draft.outdated":   "Ir nesaglabāts melnraksts no %s, ieteikums kopš tā ir mainīts.",
+	"edit.draft.apply":      "Atjaunot",
+	"edit.draft.discard":    "Atmest",
+	"edit.submit":           "Iesniegt",
+	"conflict.title":        "Konflikts %s",
+	"conflict.msg":          "Kamēr rediģēji, kāds cits saglabāja šo ieteikumu (versija %d, %s). Apvieno izmaiņas zemāk un saglabā vēlreiz.",
+	"conflict.mine":         "Tavs variants",
+	"conflict.theirs":       "Pašreizējais variants",
+	"conflict.merged":       "Apvienotais variants",
+	"conflict.submit":       "Saglabāt apvienoto",
+	"locked.msg":            "Ieteikumu \"%s\" rediģē %s kopš %s.",
+	"locked.warning":        "Ja pārņemsi rediģēšanu, %s saņems brīdinājumu. Ja abi saglabāsiet izmaiņas, otram tās būs jāapvieno.",
+	"locked.takeover":       "Pārņemt rediģēšanu",
+	"delete.title":          "Dzēst %s",
+	"delete.msg":            "Vai tiešām pārvietot ieteikumu \"%s\" uz miskasti?",
+	"translate.title":       "Tulko %s",
+	"translate.outdated":    "Ieteikums ir mainīts kopš tulkošanas (tulkots no versijas %d, pašreizējā versija %d)",
+	"translate.source":      "Oriģināls",
+	"translate.target":      "Tulkojums (%s)",
+	"translate.submit":      "Saglabāt tulkojumu",
+	"form.cancel":           "Atcelt",
+
+	// Media and moderation pages
+	"media.title":        "Faili",
+	"media.upload":       "Augšupielādēt",
+	"media.insert":       "Ievietot",
+	"media.delete":       "Dzēst",
+	"media.empty":        "Nav augšupielādētu failu",
+	"moderation.title":   "Komentāru moderācija",
+	"moderation.on":      "pie",
+	"moderation.approve": "Apstiprināt",
+	"moderation.reject":  "Noraidīt",
+	"moderation.empty":   "Nav komentāru, kas gaida apstiprināšanu",
+
+	// Editor status messages, used by js/edit.js
+	"draft.saved":   "Melnraksts saglabāts",
+	"draft.failed":  "Melnrakstu neizdevās saglabāt",
+	"draft.expired": "Sesija beigusies, melnraksts netiek saglabāts. Ielogojies citā cilnē.",
+	"lock.merge":    "Saglabājot būs jāapvieno izmaiņas.",
+
+	// Why a comment was rejected, by the key in the redirect back to the post
+	"comment.empty":     "Komentārs nedrīkst būt tukšs",
+	"comment.author":    "Vārdam jābūt no 1 līdz %d simboliem",
+	"comment.long":      "Komentārs nedrīkst būt garāks par %d simboliem",
+	"comment.links":     "Komentārā var būt ne vairāk kā %d saites",
+	"comment.rate":      "Pārāk daudz komentāru, mēģini vēlāk",
+	"comment.duplicate": "Šāds komentārs jau ir iesniegts",
+
+	// Error pages by status code, the message is shown when the error has none for visitors
+	"error.400":     "Nederīgs pieprasījums",
+	"error.400.msg": "Pieprasījumu nevar izpildīt, pārbaudi adresi vai ievadītos datus.",
+	"error.403":     "Nav atļauts",
+	"error.403.msg": "Lai to darītu, jāielogojas.",
+	"error.404":     "Nav atrasts",
+	"error.404.msg": "Šāda ieteikuma vai lapas nav, iespējams tā ir dzēsta.",
+	"error.409":     "Konflikts",
+	"error.409.msg": "To kāds cits pa to laiku ir mainījis, mēģini vēlreiz.",
+	"error.500":     "Servera kļūda",
+	"error.500.msg": "Serverī notika kļūda, mēģini vēlāk.",
+	"error.posts":   "Uz ieteikumiem",
+	"error.home":    "Uz sākumlapu",
+}
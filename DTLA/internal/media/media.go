@@ -0,0 +1,159 @@
+package media
+
+import (
+	"bytes"
+	"crypto/rand"
+	"dtla/internal/apperr"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type File struct {
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"modTime"`
+}
+
+// Content types accepted for upload mapped to the extension the stored file gets.
+// SVG is left out on purpose because it can contain scripts.
+var allowedTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"video/webm": ".webm",
+}
+
+var ErrType error = apperr.NewT(apperr.BadRequest, "err.media.type")
+var ErrSize error = apperr.NewT(apperr.BadRequest, "err.media.size")
+var ErrName error = apperr.NewT(apperr.BadRequest, "err.media.name")
+
+// Names generated by Save(), anything else is rejected by Delete()
+var validName *regexp.Regexp = regexp.MustCompile(`^[0-9a-f]{32}\.[a-z]+$`)
+
+// Store the file read from `src` in `dir` under a randomly generated name.
+// The content type is sniffed from the data itself, not taken from the client.
+func Save(dir string, urlPrefix string, src io.Reader, maxSize int64) (*File, error) {
+	var err error
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return nil, ErrType
+	}
+
+	nameBytes := make([]byte, 16)
+	_, err = rand.Read(nameBytes)
+	if err != nil {
+		return nil, err
+	}
+	name := hex.EncodeToString(nameBytes) + ext
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := filepath.Join(dir, name)
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read one byte past the limit to know if it was exceeded
+	size, err := io.Copy(file, io.LimitReader(io.MultiReader(bytes.NewReader(head), src), maxSize+1))
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && size > maxSize {
+		err = ErrSize
+	}
+	if err != nil {
+		os.Remove(filename)
+		return nil, err
+	}
+
+	return &File{
+		Name:        name,
+		URL:         path.Join(urlPrefix, name),
+		ContentType: contentType,
+		Size:        size,
+		ModTime:     time.Now(),
+	}, nil
+}
+
+// List uploaded files in `dir`, newest first
+func List(dir string, urlPrefix string) ([]*File, error) {
+	var err error
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []*File
+	for _, entry := range entries {
+		if entry.IsDir() || !validName.MatchString(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		file := &File{
+			Name:    entry.Name(),
+			URL:     path.Join(urlPrefix, entry.Name()),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}
+		for contentType, ext := range allowedTypes {
+			if filepath.Ext(file.Name) == ext {
+				file.ContentType = contentType
+			}
+		}
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime.After(files[j].ModTime)
+	})
+
+	return files, nil
+}
+
+func Delete(dir string, name string) error {
+	if !validName.MatchString(name) {
+		return ErrName
+	}
+
+	return os.Remove(filepath.Join(dir, name))
+}
+
+// Used in templates to tell images apart from videos
+func (f *File) IsImage() bool {
+	return strings.HasPrefix(f.ContentType, "image/")
+}
@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Before migrations the schema was upgraded in place at startup, so a database
+// without recorded migrations can be at any point up to legacyVersion. Such
+// databases are brought up to legacyVersion the old way and the migrations up
+// to it are recorded as applied.
+const legacyVersion = 5
+
+// Columns the old upgrade added to posts, matching 0002_post_meta.up.sql
+var legacyColumns = []struct {
+	name string
+	def  string
+	// Run once after the column is added to fill in existing rows
+	fill string
+}{
+	{name: "tags", def: "TEXT NOT NULL DEFAULT ''"},
+	{name: "created", def: "INT NOT NULL DEFAULT 0", fill: "UPDATE posts SET created = unixepoch()"},
+	{name: "updated", def: "INT NOT NULL DEFAULT 0", fill: "UPDATE posts SET updated = created"},
+	{name: "version", def: "INT NOT NULL DEFAULT 1"},
+	{name: "deleted", def: "INT"},
+	{name: "deleted_by", def: "TEXT NOT NULL DEFAULT ''"},
+}
+
+func adoptLegacy(db *sql.DB, migrations []Migration) error {
+	var err error
+
+	var recorded, hasPosts bool
+	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations)").Scan(&recorded)
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_schema WHERE type = 'table' AND name = 'posts')").Scan(&hasPosts)
+	if err != nil {
+		return err
+	}
+	if recorded || !hasPosts {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	existing, err := columns(tx, "posts")
+	if err != nil {
+		return err
+	}
+
+	for _, col := range legacyColumns {
+		if existing[col.name] {
+			continue
+		}
+
+		_, err = tx.Exec("ALTER TABLE posts ADD COLUMN " + col.name + " " + col.def)
+		if err != nil {
+			return err
+		}
+
+		if col.fill != "" {
+			_, err = tx.Exec(col.fill)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, m := range migrations {
+		if m.Version > legacyVersion {
+			break
+		}
+
+		// The tables added after the posts columns were always created
+		// with IF NOT EXISTS, so these can run again
+		if m.Version > 2 {
+			_, err = tx.Exec(m.Up)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec("INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)", m.Version, m.Name, time.Now().Unix())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func columns(tx *sql.Tx, table string) (map[string]bool, error) {
+	var err error
+
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		existing[name] = true
+	}
+
+	return existing, rows.Err()
+}
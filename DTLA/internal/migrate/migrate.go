@@ -0,0 +1,336 @@
+package migrate
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Versioned schema changes embedded in the binary. Each migration is a pair
+// of files in migrations/, NNNN_name.up.sql and NNNN_name.down.sql, run in
+// a transaction. Applied versions are recorded in the schema_migrations table.
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// A migration and when it was applied, zero if it wasn't
+type State struct {
+	Migration
+	Applied time.Time
+}
+
+var ErrPending error = errors.New("Datubāzei ir nepielietotas migrācijas, palaid 'dtla migrate up'")
+
+var fileNameRe *regexp.Regexp = regexp.MustCompile(`^([0-9]+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// All migrations sorted by version
+func Load() ([]Migration, error) {
+	var err error
+
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNameRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("Nederīgs migrācijas faila nosaukums: %s", entry.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := fs.ReadFile(files, path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("Migrācijai %04d_%s trūkst up vai down faila", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Apply all migrations that haven't been applied yet
+func Up(db *sql.DB) ([]Migration, error) {
+	var err error
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	err = createTable(db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = adoptLegacy(db, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		err = run(db, m.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)", m.Version, m.Name, time.Now().Unix())
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("%04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// Revert the last `steps` applied migrations, newest first
+func Down(db *sql.DB, steps int) ([]Migration, error) {
+	var err error
+
+	states, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(states) - 1; i >= 0 && len(done) < steps; i-- {
+		m := states[i]
+		if m.Applied.IsZero() {
+			continue
+		}
+
+		err = run(db, m.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version IS ?", m.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("%04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m.Migration)
+	}
+
+	return done, nil
+}
+
+// Every migration and whether it's applied
+func Status(db *sql.DB) ([]State, error) {
+	var err error
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only reads, without the table nothing has been applied
+	applied := make(map[int]time.Time)
+	exists, err := tableExists(db, "schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		applied, err = appliedVersions(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var states []State
+	for _, m := range migrations {
+		states = append(states, State{Migration: m, Applied: applied[m.Version]})
+	}
+
+	return states, nil
+}
+
+// Returns ErrPending if Up() has something to do, without applying anything
+func Check(db *sql.DB) error {
+	var err error
+
+	states, err := Status(db)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range states {
+		if s.Applied.IsZero() {
+			return ErrPending
+		}
+	}
+
+	return nil
+}
+
+// Tables every migrated database has
+var coreTables []string = []string{"users", "posts"}
+
+// Check that db was migrated by this version of the program or an older one,
+// without changing it. Pending migrations are fine, Up() applies them later.
+func Verify(db *sql.DB) error {
+	var err error
+
+	migrations, err := Load()
+	if err != nil {
+		return err
+	}
+
+	// Created by the first migration and by versions from before migrations,
+	// so any database of this program has them
+	for _, table := range coreTables {
+		exists, err := tableExists(db, table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("Datubāzē nav tabulas '%s'", table)
+		}
+	}
+
+	exists, err := tableExists(db, "schema_migrations")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		// From before migrations, Up() adopts it like one with an empty table
+		return nil
+	}
+
+	known := make(map[int]string)
+	for _, m := range migrations {
+		known[m.Version] = m.Name
+	}
+
+	rows, err := db.Query("SELECT version, name FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var name string
+		err = rows.Scan(&version, &name)
+		if err != nil {
+			return err
+		}
+		if known[version] != name {
+			return fmt.Errorf("Nezināma migrācija %04d_%s, datubāze ir no jaunākas programmas versijas", version, name)
+		}
+	}
+
+	return rows.Err()
+}
+
+func tableExists(db *sql.DB, name string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type IS 'table' AND name IS ?", name).Scan(&count)
+	return count > 0, err
+}
+
+func createTable(db *sql.DB) error {
+	var err error
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY NOT NULL,
+		name TEXT NOT NULL,
+		applied INT NOT NULL)`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func appliedVersions(db *sql.DB) (map[int]time.Time, error) {
+	var err error
+
+	rows, err := db.Query("SELECT version, applied FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var unix int64
+		err = rows.Scan(&version, &unix)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = time.Unix(unix, 0)
+	}
+
+	return applied, rows.Err()
+}
+
+// Run the statements of a migration and record it in the same transaction
+func run(db *sql.DB, statements string, record func(tx *sql.Tx) error) error {
+	var err error
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(statements)
+	if err != nil {
+		return err
+	}
+
+	err = record(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
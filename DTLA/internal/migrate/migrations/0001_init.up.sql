@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS users (id INTEGER PRIMARY KEY NOT NULL, user TEXT NOT NULL UNIQUE, pswd TEXT NOT NULL, sid TEXT UNIQUE, sstart INT, sage INT);
+CREATE TABLE IF NOT EXISTS posts (
+id INTEGER PRIMARY KEY NOT NULL,
+title TEXT,
+desc TEXT,
+body TEXT);
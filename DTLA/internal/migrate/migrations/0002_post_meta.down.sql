@@ -0,0 +1,6 @@
+ALTER TABLE posts DROP COLUMN deleted_by;
+ALTER TABLE posts DROP COLUMN deleted;
+ALTER TABLE posts DROP COLUMN version;
+ALTER TABLE posts DROP COLUMN updated;
+ALTER TABLE posts DROP COLUMN created;
+ALTER TABLE posts DROP COLUMN tags;
@@ -0,0 +1,9 @@
+-- Tags, timestamps, the version used to detect conflicting saves and the trash
+ALTER TABLE posts ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN created INT NOT NULL DEFAULT 0;
+UPDATE posts SET created = unixepoch();
+ALTER TABLE posts ADD COLUMN updated INT NOT NULL DEFAULT 0;
+UPDATE posts SET updated = created;
+ALTER TABLE posts ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE posts ADD COLUMN deleted INT;
+ALTER TABLE posts ADD COLUMN deleted_by TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP TRIGGER comments_post_delete;
+DROP TABLE comments;
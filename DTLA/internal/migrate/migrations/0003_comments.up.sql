@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS comments (
+	id INTEGER PRIMARY KEY NOT NULL,
+	post_id INTEGER NOT NULL,
+	author TEXT NOT NULL,
+	user_id INTEGER NOT NULL DEFAULT 0,
+	body TEXT NOT NULL,
+	created INT NOT NULL,
+	status INT NOT NULL DEFAULT 0,
+	ip TEXT NOT NULL DEFAULT '');
+
+CREATE INDEX IF NOT EXISTS comments_post_id ON comments (post_id, status);
+
+-- Comments go away together with a purged post
+CREATE TRIGGER IF NOT EXISTS comments_post_delete AFTER DELETE ON posts
+BEGIN DELETE FROM comments WHERE post_id = old.id; END;
@@ -0,0 +1,2 @@
+DROP TRIGGER translations_post_delete;
+DROP TABLE translations;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS translations (
+	post_id INTEGER NOT NULL,
+	lang TEXT NOT NULL,
+	title TEXT NOT NULL,
+	desc TEXT NOT NULL,
+	body TEXT NOT NULL,
+	source_version INT NOT NULL,
+	updated INT NOT NULL,
+	PRIMARY KEY (post_id, lang));
+
+CREATE TRIGGER IF NOT EXISTS translations_post_delete AFTER DELETE ON posts
+BEGIN DELETE FROM translations WHERE post_id = old.id; END;
@@ -0,0 +1,2 @@
+DROP TRIGGER drafts_post_delete;
+DROP TABLE drafts;
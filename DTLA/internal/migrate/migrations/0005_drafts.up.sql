@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS drafts (
+	post_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	desc TEXT NOT NULL,
+	body TEXT NOT NULL,
+	tags TEXT NOT NULL,
+	base_version INT NOT NULL,
+	saved INT NOT NULL,
+	PRIMARY KEY (post_id, user_id));
+
+CREATE TRIGGER IF NOT EXISTS drafts_post_delete AFTER DELETE ON posts
+BEGIN DELETE FROM drafts WHERE post_id = old.id; END;
@@ -0,0 +1,118 @@
+package post
+
+import (
+	"context"
+	"database/sql"
+	"dtla/internal/database"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Drafts are autosaved copies of the edit form, one per post and user,
+// kept apart from the post until the editor saves or discards them
+
+type Draft struct {
+	PostID int      `json:"postId"`
+	UserID int      `json:"userId"`
+	Title  string   `json:"title"`
+	Desc   string   `json:"desc"`
+	Body   string   `json:"body"`
+	Tags   []string `json:"tags"`
+
+	// Version of the post the editor started from, used as the version
+	// when the draft is applied so saving it can still conflict
+	BaseVersion int       `json:"baseVersion"`
+	Saved       time.Time `json:"saved"`
+}
+
+func GetDraft(ctx context.Context, db *sql.DB, postID int, userID int) (*Draft, error) {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	d := Draft{PostID: postID, UserID: userID}
+	var tags string
+	var saved int64
+	err = db.QueryRowContext(ctx, "SELECT title, desc, body, tags, base_version, saved FROM drafts WHERE post_id IS ? AND user_id IS ?", postID, userID).
+		Scan(&d.Title, &d.Desc, &d.Body, &tags, &d.BaseVersion, &saved)
+	if err != nil {
+		return nil, err
+	}
+	d.Tags = ParseTags(tags)
+	d.Saved = time.Unix(saved, 0)
+
+	return &d, nil
+}
+
+// Load the draft from the same fields as Page.LoadForm(), without the
+// checks, a draft doesn't have to be valid until it's saved as the post
+func (d *Draft) LoadForm(r *http.Request) error {
+	var err error
+
+	err = r.ParseForm()
+	if err != nil {
+		return err
+	}
+
+	d.BaseVersion, err = strconv.Atoi(r.PostFormValue("post-version"))
+	if err != nil {
+		return err
+	}
+
+	d.Title = r.PostFormValue("post-title")
+	d.Desc = r.PostFormValue("post-desc")
+	d.Body = r.PostFormValue("post-body")
+	d.Tags = ParseTags(r.PostFormValue("post-tags"))
+
+	if len(d.Body) > MaxBodyLen {
+		return ErrBodyLong
+	}
+
+	return nil
+}
+
+// Add or replace the user's draft of the post
+func (d *Draft) Save(ctx context.Context, db *sql.DB) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	d.Saved = time.Unix(time.Now().Unix(), 0)
+	_, err = db.ExecContext(ctx, `INSERT INTO drafts (post_id, user_id, title, desc, body, tags, base_version, saved) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (post_id, user_id) DO UPDATE SET title = excluded.title, desc = excluded.desc, body = excluded.body,
+		tags = excluded.tags, base_version = excluded.base_version, saved = excluded.saved`,
+		d.PostID, d.UserID, d.Title, d.Desc, d.Body, strings.Join(d.Tags, ","), d.BaseVersion, d.Saved.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Used both when the draft is discarded and when the post is saved
+func DeleteDraft(ctx context.Context, db *sql.DB, postID int, userID int) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	_, err = db.ExecContext(ctx, "DELETE FROM drafts WHERE post_id IS ? AND user_id IS ?", postID, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// The post as the draft would change it
+func (d *Draft) Apply(p *Page) {
+	p.Title = d.Title
+	p.Desc = d.Desc
+	p.Body = d.Body
+	p.Tags = d.Tags
+	p.Version = d.BaseVersion
+}
@@ -0,0 +1,127 @@
+package post
+
+import (
+	"bytes"
+	"dtla/internal/util"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Posts exported to files are Markdown documents with YAML front matter.
+// The body is stored as is, so it's HTML, which Markdown allows.
+
+type frontMatter struct {
+	ID      int       `yaml:"id"`
+	Title   string    `yaml:"title"`
+	Desc    string    `yaml:"desc"`
+	Tags    []string  `yaml:"tags,flow"`
+	Created time.Time `yaml:"created"`
+	Updated time.Time `yaml:"updated"`
+}
+
+var ErrFrontMatter error = errors.New("Failam nav YAML front matter")
+
+var fileNameRe *regexp.Regexp = regexp.MustCompile(`^[0-9]+-[a-z0-9-]*\.md$`)
+
+// Name of the file the post is exported to, e.g. "0001-hash-jaucejfunkcija.md"
+func FileName(p *Page) string {
+	return fmt.Sprintf("%04d-%s.md", p.ID, util.Slugify(p.Title))
+}
+
+// Names of exported post files in `dir`
+func ListFiles(dir string) ([]string, error) {
+	var err error
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && fileNameRe.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+func WriteFile(filename string, p *Page) error {
+	var err error
+
+	fm := frontMatter{
+		ID:      p.ID,
+		Title:   p.Title,
+		Desc:    p.Desc,
+		Tags:    p.Tags,
+		Created: p.Created.UTC(),
+		Updated: p.Updated.UTC(),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	enc := yaml.NewEncoder(&buf)
+	err = enc.Encode(&fm)
+	if err != nil {
+		return err
+	}
+	err = enc.Close()
+	if err != nil {
+		return err
+	}
+	buf.WriteString("---\n")
+	buf.WriteString(p.Body)
+
+	return os.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+func ReadFile(filename string) (*Page, error) {
+	var err error
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	// Files checked out on Windows can have CRLF line endings
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	rest, found := strings.CutPrefix(content, "---\n")
+	if !found {
+		return nil, ErrFrontMatter
+	}
+
+	header, body, found := strings.Cut(rest, "\n---\n")
+	if !found {
+		return nil, ErrFrontMatter
+	}
+
+	var fm frontMatter
+	err = yaml.Unmarshal([]byte(header), &fm)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Page{
+		ID:      fm.ID,
+		Title:   fm.Title,
+		Desc:    fm.Desc,
+		Body:    body,
+		Tags:    ParseTags(strings.Join(fm.Tags, ",")),
+		Created: fm.Created,
+		Updated: fm.Updated,
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -1,36 +1,85 @@
 package post
 
 import (
+	"context"
 	"database/sql"
+	"dtla/internal/apperr"
+	"dtla/internal/database"
+	"encoding/json"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type Page struct {
-	ID    int
-	Title string
-	Desc  string
-	Body  string
+	ID      int       `json:"id"`
+	Title   string    `json:"title"`
+	Desc    string    `json:"desc"`
+	Body    string    `json:"body,omitempty"`
+	Tags    []string  `json:"tags"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+
+	// Incremented on every save, a save with an older version fails with ErrConflict
+	Version int `json:"version"`
+
+	// Set when the post is in the trash
+	Deleted   time.Time `json:"-"`
+	DeletedBy string    `json:"-"`
 }
 
-func GetPage(db *sql.DB, id int) (*Page, error) {
+const (
+	MaxTitleLen = 200
+	MaxDescLen  = 1000
+	MaxBodyLen  = 1 << 20
+)
+
+var ErrTitleEmpty error = apperr.NewT(apperr.BadRequest, "err.post.title-empty")
+var ErrTitleLong error = apperr.NewT(apperr.BadRequest, "err.post.title-long", MaxTitleLen)
+var ErrDescLong error = apperr.NewT(apperr.BadRequest, "err.post.desc-long", MaxDescLen)
+var ErrBodyLong error = apperr.NewT(apperr.BadRequest, "err.post.body-long", MaxBodyLen)
+
+// Returned by Save() when the post was changed by someone else since it was loaded
+var ErrConflict error = apperr.NewT(apperr.Conflict, "err.post.conflict")
+
+func GetPage(ctx context.Context, db *sql.DB, id int) (*Page, error) {
 	var err error
 
-	row := db.QueryRow("SELECT title, desc, body FROM posts WHERE id IS ?", id)
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	row := db.QueryRowContext(ctx, "SELECT title, desc, body, tags, created, updated, version FROM posts WHERE id IS ? AND deleted IS NULL", id)
 	var p *Page = new(Page)
 	p.ID = id
-	err = row.Scan(&p.Title, &p.Desc, &p.Body)
+	var tags string
+	var created, updated int64
+	err = row.Scan(&p.Title, &p.Desc, &p.Body, &tags, &created, &updated, &p.Version)
 	if err != nil {
 		return nil, err
 	}
+	p.Tags = ParseTags(tags)
+	p.Created = time.Unix(created, 0)
+	p.Updated = time.Unix(updated, 0)
 
 	return p, nil
 }
 
-func GetAllPages(db *sql.DB) (*[]*Page, error) {
+// All posts that aren't in the trash, without the body
+func GetAllPages(ctx context.Context, db *sql.DB) (*[]*Page, error) {
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	return queryPages(ctx, db, "WHERE deleted IS NULL")
+}
+
+func queryPages(ctx context.Context, db *sql.DB, where string, args ...any) (*[]*Page, error) {
 	var err error
 
-	rows, err := db.Query("SELECT id, title, desc FROM posts")
+	rows, err := db.QueryContext(ctx, "SELECT id, title, desc, tags, created, updated, version, deleted, deleted_by FROM posts "+where, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -40,16 +89,51 @@ func GetAllPages(db *sql.DB) (*[]*Page, error) {
 
 	for rows.Next() != false {
 		var page *Page = new(Page)
-		err = rows.Scan(&page.ID, &page.Title, &page.Desc)
+		var tags string
+		var created, updated int64
+		var deleted sql.NullInt64
+		err = rows.Scan(&page.ID, &page.Title, &page.Desc, &tags, &created, &updated, &page.Version, &deleted, &page.DeletedBy)
 		if err != nil {
 			return nil, err
 		}
+		page.Tags = ParseTags(tags)
+		page.Created = time.Unix(created, 0)
+		page.Updated = time.Unix(updated, 0)
+		if deleted.Valid {
+			page.Deleted = time.Unix(deleted.Int64, 0)
+		}
 		*pages = append(*pages, page)
 	}
 
 	return pages, nil
 }
 
+// Create a new post, ID, Created and Updated are set from the database row
+func (p *Page) Insert(ctx context.Context, db *sql.DB) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	// Stored with second precision
+	p.Created = time.Unix(time.Now().Unix(), 0)
+	p.Updated = p.Created
+	p.Version = 1
+	res, err := db.ExecContext(ctx, "INSERT INTO posts (title, desc, body, tags, created, updated) VALUES (?, ?, ?, ?, ?, ?)",
+		p.Title, p.Desc, p.Body, strings.Join(p.Tags, ","), p.Created.Unix(), p.Updated.Unix())
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	p.ID = int(id)
+
+	return nil
+}
+
 func (p *Page) LoadForm(r *http.Request) error {
 	var err error
 
@@ -58,6 +142,18 @@ func (p *Page) LoadForm(r *http.Request) error {
 		return err
 	}
 
+	p.Version, err = strconv.Atoi(r.PostFormValue("post-version"))
+	if err != nil {
+		return err
+	}
+
+	return p.LoadNewForm(r)
+}
+
+// Load the fields of a post that doesn't exist yet from the "new" and "edit" forms
+func (p *Page) LoadNewForm(r *http.Request) error {
+	var err error
+
 	err = r.ParseForm()
 	if err != nil {
 		return err
@@ -66,17 +162,181 @@ func (p *Page) LoadForm(r *http.Request) error {
 	p.Title = r.PostFormValue("post-title")
 	p.Desc = r.PostFormValue("post-desc")
 	p.Body = r.PostFormValue("post-body")
+	p.Tags = ParseTags(r.PostFormValue("post-tags"))
+
+	return p.Validate()
+}
+
+// Used to warn about creating a post with the same title as an existing one
+func TitleExists(ctx context.Context, db *sql.DB, title string) (bool, error) {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	var exists bool
+	err = db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM posts WHERE title IS ? AND deleted IS NULL)", title).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// Fields of a post that can be set through the API
+type pageJSON struct {
+	Title string   `json:"title"`
+	Desc  string   `json:"desc"`
+	Body  string   `json:"body"`
+	Tags  []string `json:"tags"`
+
+	// Optional, if set the update fails when the post has a different version
+	Version int `json:"version"`
+}
+
+// Counterpart of LoadForm() for the JSON API, ID is left for the caller to set
+func (p *Page) LoadJSON(r io.Reader) error {
+	var err error
+
+	var in pageJSON
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&in)
+	if err != nil {
+		return apperr.NewT(apperr.BadRequest, "err.post.json", err.Error())
+	}
+
+	p.Title = in.Title
+	p.Desc = in.Desc
+	p.Body = in.Body
+	p.Tags = ParseTags(strings.Join(in.Tags, ","))
+	if in.Version != 0 {
+		p.Version = in.Version
+	}
+
+	return p.Validate()
+}
+
+// Checks shared by every way a post can be created or changed
+func (p *Page) Validate() error {
+	p.Title = strings.TrimSpace(p.Title)
+
+	if p.Title == "" {
+		return ErrTitleEmpty
+	}
+	if utf8.RuneCountInString(p.Title) > MaxTitleLen {
+		return ErrTitleLong
+	}
+	if utf8.RuneCountInString(p.Desc) > MaxDescLen {
+		return ErrDescLong
+	}
+	if len(p.Body) > MaxBodyLen {
+		return ErrBodyLong
+	}
 
 	return nil
 }
 
-func (p *Page) Save(db *sql.DB) error {
+// Update the post if it still has the version it was loaded with
+func (p *Page) Save(ctx context.Context, db *sql.DB) error {
 	var err error
 
-	_, err = db.Exec("UPDATE posts SET title = ?, desc = ?, body = ? WHERE id IS ?", p.Title, p.Desc, p.Body, p.ID)
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	updated := time.Unix(time.Now().Unix(), 0)
+	res, err := db.ExecContext(ctx, "UPDATE posts SET title = ?, desc = ?, body = ?, tags = ?, updated = ?, version = version + 1 WHERE id IS ? AND version IS ? AND deleted IS NULL",
+		p.Title, p.Desc, p.Body, strings.Join(p.Tags, ","), updated.Unix(), p.ID, p.Version)
 	if err != nil {
 		return err
 	}
 
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// Either the post is gone or the version didn't match
+		var exists bool
+		err = db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM posts WHERE id IS ? AND deleted IS NULL)", p.ID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return sql.ErrNoRows
+		}
+		return ErrConflict
+	}
+
+	p.Updated = updated
+	p.Version++
+
 	return nil
 }
+
+// Satisfied by both *sql.DB and *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Store the post with its own ID and timestamps, replacing the post with the same ID.
+// A replaced post gets a new version so editors that had it open get a conflict
+// and is taken out of the trash if it was there.
+// Used when importing, a post without an ID is inserted as a new one.
+func (p *Page) Put(ctx context.Context, db Execer) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	if p.ID == 0 {
+		if p.Created.IsZero() {
+			p.Created = time.Unix(time.Now().Unix(), 0)
+		}
+		if p.Updated.IsZero() {
+			p.Updated = p.Created
+		}
+		res, err := db.ExecContext(ctx, "INSERT INTO posts (title, desc, body, tags, created, updated) VALUES (?, ?, ?, ?, ?, ?)",
+			p.Title, p.Desc, p.Body, strings.Join(p.Tags, ","), p.Created.Unix(), p.Updated.Unix())
+		if err != nil {
+			return err
+		}
+		p.Version = 1
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		p.ID = int(id)
+		return nil
+	}
+
+	_, err = db.ExecContext(ctx, `INSERT INTO posts (id, title, desc, body, tags, created, updated) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET title = excluded.title, desc = excluded.desc, body = excluded.body,
+		tags = excluded.tags, created = excluded.created, updated = excluded.updated, version = posts.version + 1,
+		deleted = NULL, deleted_by = ''`,
+		p.ID, p.Title, p.Desc, p.Body, strings.Join(p.Tags, ","), p.Created.Unix(), p.Updated.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *Page) HasTag(tag string) bool {
+	return slices.Contains(p.Tags, tag)
+}
+
+// Tags are stored and entered as a comma separated list
+func ParseTags(s string) []string {
+	tags := []string{}
+
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
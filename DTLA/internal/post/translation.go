@@ -0,0 +1,198 @@
+package post
+
+import (
+	"context"
+	"database/sql"
+	"dtla/internal/apperr"
+	"dtla/internal/database"
+	"errors"
+	"slices"
+	"time"
+)
+
+// Posts are written in DefaultLang and stored in the posts table.
+// Translations into the other languages are stored separately and
+// linked to the post by its ID, so tags, comments and history stay shared.
+
+const DefaultLang = "lv"
+
+// Languages the site can be read in, DefaultLang first
+var Languages = []string{DefaultLang, "en"}
+
+var ErrLang error = apperr.NewT(apperr.BadRequest, "err.post.lang")
+
+type Translation struct {
+	PostID int    `json:"postId"`
+	Lang   string `json:"lang"`
+	Title  string `json:"title"`
+	Desc   string `json:"desc"`
+	Body   string `json:"body"`
+
+	// Version of the post the translation was made from,
+	// older than the post's version means the post changed since
+	SourceVersion int       `json:"sourceVersion"`
+	Updated       time.Time `json:"updated"`
+}
+
+// Shown to editors for each language a post can be translated into
+type TranslationStatus struct {
+	Lang     string `json:"lang"`
+	Missing  bool   `json:"missing"`
+	Outdated bool   `json:"outdated"`
+}
+
+func ValidLang(lang string) bool {
+	return slices.Contains(Languages, lang)
+}
+
+func GetTranslation(ctx context.Context, db *sql.DB, postID int, lang string) (*Translation, error) {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	t := Translation{PostID: postID, Lang: lang}
+	var updated int64
+	err = db.QueryRowContext(ctx, "SELECT title, desc, body, source_version, updated FROM translations WHERE post_id IS ? AND lang IS ?", postID, lang).
+		Scan(&t.Title, &t.Desc, &t.Body, &t.SourceVersion, &updated)
+	if err != nil {
+		return nil, err
+	}
+	t.Updated = time.Unix(updated, 0)
+
+	return &t, nil
+}
+
+// Whether each language other than DefaultLang has an up to date translation of the post
+func GetTranslationStatus(ctx context.Context, db *sql.DB, p *Page) ([]TranslationStatus, error) {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	rows, err := db.QueryContext(ctx, "SELECT lang, source_version FROM translations WHERE post_id IS ?", p.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[string]int)
+	for rows.Next() {
+		var lang string
+		var version int
+		err = rows.Scan(&lang, &version)
+		if err != nil {
+			return nil, err
+		}
+		versions[lang] = version
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	var status []TranslationStatus
+	for _, lang := range Languages[1:] {
+		version, ok := versions[lang]
+		status = append(status, TranslationStatus{
+			Lang:     lang,
+			Missing:  !ok,
+			Outdated: ok && version < p.Version,
+		})
+	}
+
+	return status, nil
+}
+
+// Add or replace the translation
+func (t *Translation) Save(ctx context.Context, db *sql.DB) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	if t.Lang == DefaultLang || !ValidLang(t.Lang) {
+		return ErrLang
+	}
+
+	// Same limits as for the post itself
+	err = (&Page{Title: t.Title, Desc: t.Desc, Body: t.Body}).Validate()
+	if err != nil {
+		return err
+	}
+
+	t.Updated = time.Unix(time.Now().Unix(), 0)
+	_, err = db.ExecContext(ctx, `INSERT INTO translations (post_id, lang, title, desc, body, source_version, updated) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (post_id, lang) DO UPDATE SET title = excluded.title, desc = excluded.desc, body = excluded.body,
+		source_version = excluded.source_version, updated = excluded.updated`,
+		t.PostID, t.Lang, t.Title, t.Desc, t.Body, t.SourceVersion, t.Updated.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Replace the text of the post with its translation into lang.
+// Returns false and leaves the post as is when there is no translation.
+func (p *Page) Translate(ctx context.Context, db *sql.DB, lang string) (bool, error) {
+	var err error
+
+	if lang == DefaultLang {
+		return true, nil
+	}
+
+	t, err := GetTranslation(ctx, db, p.ID, lang)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	p.Title = t.Title
+	p.Desc = t.Desc
+	p.Body = t.Body
+
+	return true, nil
+}
+
+// Translate() for a list of posts from GetAllPages(), only the title and description
+func TranslateAll(ctx context.Context, db *sql.DB, pages *[]*Page, lang string) error {
+	var err error
+
+	if lang == DefaultLang {
+		return nil
+	}
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	rows, err := db.QueryContext(ctx, "SELECT post_id, title, desc FROM translations WHERE lang IS ?", lang)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*Page)
+	for _, p := range *pages {
+		byID[p.ID] = p
+	}
+
+	for rows.Next() {
+		var id int
+		var title, desc string
+		err = rows.Scan(&id, &title, &desc)
+		if err != nil {
+			return err
+		}
+		p, ok := byID[id]
+		if !ok {
+			continue
+		}
+		p.Title = title
+		p.Desc = desc
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,92 @@
+package post
+
+import (
+	"context"
+	"database/sql"
+	"dtla/internal/database"
+	"time"
+)
+
+// Deleting a post moves it to the trash, from where it can be restored
+// or purged, which deletes it for good
+
+// Posts in the trash, most recently deleted first
+func GetTrash(ctx context.Context, db *sql.DB) (*[]*Page, error) {
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	return queryPages(ctx, db, "WHERE deleted IS NOT NULL ORDER BY deleted DESC")
+}
+
+func Trash(ctx context.Context, db Execer, id int, user string) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	res, err := db.ExecContext(ctx, "UPDATE posts SET deleted = ?, deleted_by = ? WHERE id IS ? AND deleted IS NULL", time.Now().Unix(), user, id)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res)
+}
+
+func Restore(ctx context.Context, db Execer, id int) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	res, err := db.ExecContext(ctx, "UPDATE posts SET deleted = NULL, deleted_by = '' WHERE id IS ? AND deleted IS NOT NULL", id)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res)
+}
+
+// Delete a post in the trash
+func Purge(ctx context.Context, db Execer, id int) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM posts WHERE id IS ? AND deleted IS NOT NULL", id)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res)
+}
+
+// Delete posts that were moved to the trash before `before`
+func PurgeOlder(ctx context.Context, db Execer, before time.Time) (int64, error) {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM posts WHERE deleted IS NOT NULL AND deleted < ?", before.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// sql.ErrNoRows if the statement didn't change anything
+func expectRow(res sql.Result) error {
+	var err error
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package render
+
+import (
+	"dtla/internal/apperr"
+	"dtla/internal/i18n"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Shortcodes look like template actions in post bodies and expand to HTML
+// for files under the public directory:
+//
+//	{{cast "hash/sha-komanda"}}           public/cast/hash/sha-komanda.cast
+//	{{video "start"}}                     public/vid/start.webm
+//	{{figure "hash/db.png" "Apraksts"}}   public/img/ieteikumi/hash/db.png
+//
+// A path starting with / is taken from the root of the public directory
+// instead, for example {{figure "/img/media/x.png" ""}} for uploaded files.
+// Only these are expanded, any other {{ is left as it is, so posts can quote
+// template syntax like GitHub Actions' ${{ secrets.X }}.
+
+var ErrMissing error = apperr.NewT(apperr.BadRequest, "err.shortcode.missing")
+var ErrArgs error = apperr.NewT(apperr.BadRequest, "err.shortcode.args")
+
+var shortcodeRe *regexp.Regexp = regexp.MustCompile(`\{\{\s*(cast|video|figure)((?:\s+(?:"(?:[^"\\\n]|\\.)*"|` + "`[^`]*`" + `))*)\s*\}\}`)
+var argRe *regexp.Regexp = regexp.MustCompile(`"(?:[^"\\\n]|\\.)*"|` + "`[^`]*`")
+
+// Expand the shortcodes in body. Files are looked up in the public file system.
+//
+// A shortcode for a file that doesn't exist is replaced with a visible
+// placeholder in lang and reported with an error wrapping ErrMissing, the rest of
+// the body is still expanded. Any other error, one wrapping ErrArgs or a
+// quoting error, means a shortcode is malformed and the returned string is empty.
+func Shortcodes(body string, lang string, public fs.FS) (string, error) {
+	var err error
+
+	if !strings.Contains(body, "{{") {
+		return body, nil
+	}
+
+	sc := shortcodes{public: public, lang: lang}
+	out := shortcodeRe.ReplaceAllStringFunc(body, func(match string) string {
+		if err != nil {
+			return match
+		}
+
+		sub := shortcodeRe.FindStringSubmatch(match)
+		name := sub[1]
+		var args []string
+		for _, quoted := range argRe.FindAllString(sub[2], -1) {
+			arg, unquoteErr := strconv.Unquote(quoted)
+			if unquoteErr != nil {
+				err = fmt.Errorf("%s: %w", match, unquoteErr)
+				return match
+			}
+			args = append(args, arg)
+		}
+
+		switch {
+		case name == "cast" && len(args) == 1:
+			return sc.cast(args[0])
+		case name == "video" && len(args) == 1:
+			return sc.video(args[0])
+		case name == "figure" && len(args) == 2:
+			return sc.figure(args[0], args[1])
+		}
+		err = fmt.Errorf("%s: %w", match, ErrArgs)
+		return match
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return out, errors.Join(sc.missing...)
+}
+
+type shortcodes struct {
+	public  fs.FS
+	lang    string
+	casts   int
+	missing []error
+}
+
+// URL of the file and whether it exists, dir and ext are used unless name starts with /
+func (sc *shortcodes) resolve(dir string, name string, ext string) (string, bool) {
+	url := name
+	if !strings.HasPrefix(name, "/") {
+		url = "/" + dir + "/" + name + ext
+	}
+	url = path.Clean(url)
+
+	rel := strings.TrimPrefix(url, "/")
+	if !fs.ValidPath(rel) {
+		sc.missing = append(sc.missing, fmt.Errorf("%w: %s", ErrMissing, name))
+		return url, false
+	}
+
+	_, err := fs.Stat(sc.public, rel)
+	if err != nil {
+		sc.missing = append(sc.missing, fmt.Errorf("%w: %s", ErrMissing, name))
+		return url, false
+	}
+
+	return url, true
+}
+
+func (sc *shortcodes) cast(name string) string {
+	url, ok := sc.resolve("cast", name, ".cast")
+	if !ok {
+		return sc.placeholder(url)
+	}
+
+	sc.casts++
+	id := fmt.Sprintf("cast-%d", sc.casts)
+	// JSON escapes <, > and & too, so the URL can't end the script
+	jsURL, _ := json.Marshal(url)
+	return fmt.Sprintf(`<div id="%s" class="cast"></div>
+<script>AsciinemaPlayer.create(%s, document.getElementById('%s'));</script>`, id, jsURL, id)
+}
+
+func (sc *shortcodes) video(name string) string {
+	url, ok := sc.resolve("vid", name, ".webm")
+	if !ok {
+		return sc.placeholder(url)
+	}
+
+	return fmt.Sprintf(`<video src="%s" controls></video>`, html.EscapeString(url))
+}
+
+// The caption is HTML like the rest of the body
+func (sc *shortcodes) figure(name string, caption string) string {
+	url, ok := sc.resolve("img/ieteikumi", name, "")
+	if !ok {
+		return sc.placeholder(url)
+	}
+
+	if caption == "" {
+		return fmt.Sprintf(`<div class="img-and-desc">
+<img src="%s"/>
+</div>`, html.EscapeString(url))
+	}
+	return fmt.Sprintf(`<div class="img-and-desc">
+<p>%s</p>
+<img src="%s"/>
+</div>`, caption, html.EscapeString(url))
+}
+
+func (sc *shortcodes) placeholder(url string) string {
+	return `<p class="shortcode-missing">` + i18n.T(sc.lang, "err.shortcode.missing") + ": " + html.EscapeString(url) + "</p>"
+}
@@ -0,0 +1,76 @@
+package render
+
+import (
+	"dtla/internal/i18n"
+	"dtla/internal/util"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A heading in a post body, listed in the table of contents
+type Heading struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+}
+
+// h1 is the title of the post, so only h2 to h4 are sections
+var headingRe *regexp.Regexp = regexp.MustCompile(`(?is)<h([2-4])((?:\s[^>]*)?)>(.*?)</h[2-4]>`)
+var idAttrRe *regexp.Regexp = regexp.MustCompile(`(?i)\sid\s*=\s*"([^"]*)"`)
+var tagRe *regexp.Regexp = regexp.MustCompile(`<[^>]*>`)
+
+// Give every heading in body an id, keeping the ones it already has so old links
+// still work, and add an anchor to copy a link to it, titled in lang. Ids are made
+// from the heading text so they stay the same as long as the text does.
+func Headings(body string, lang string) (string, []Heading) {
+	anchorTitle := html.EscapeString(i18n.T(lang, "view.toc.anchor"))
+	var headings []Heading
+
+	// Don't reuse ids of other elements, like <p id="section-salt">
+	used := make(map[string]bool)
+	for _, match := range idAttrRe.FindAllStringSubmatch(body, -1) {
+		used[match[1]] = true
+	}
+
+	body = headingRe.ReplaceAllStringFunc(body, func(match string) string {
+		parts := headingRe.FindStringSubmatch(match)
+		level, _ := strconv.Atoi(parts[1])
+		attrs := parts[2]
+		content := parts[3]
+
+		text := strings.TrimSpace(html.UnescapeString(tagRe.ReplaceAllString(content, "")))
+
+		var id string
+		idMatch := idAttrRe.FindStringSubmatch(attrs)
+		if idMatch != nil {
+			id = idMatch[1]
+		} else {
+			id = uniqueID(used, util.Slugify(text), len(headings)+1)
+			attrs += ` id="` + id + `"`
+		}
+
+		headings = append(headings, Heading{Level: level, ID: id, Text: text})
+
+		return "<h" + parts[1] + attrs + ">" + content +
+			` <a href="#` + html.EscapeString(id) + `" class="heading-anchor" title="` + anchorTitle + `">#</a>` +
+			"</h" + parts[1] + ">"
+	})
+
+	return body, headings
+}
+
+func uniqueID(used map[string]bool, slug string, n int) string {
+	if slug == "" {
+		slug = "sadala-" + strconv.Itoa(n)
+	}
+
+	id := slug
+	for i := 2; used[id]; i++ {
+		id = slug + "-" + strconv.Itoa(i)
+	}
+	used[id] = true
+
+	return id
+}
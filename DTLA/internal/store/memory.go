@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"dtla/internal/post"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PostStore kept in memory, nothing is saved when the program exits
+type MemPosts struct {
+	mu     sync.Mutex
+	posts  map[int]*post.Page
+	nextID int
+}
+
+func NewMemPosts() *MemPosts {
+	return &MemPosts{posts: make(map[int]*post.Page), nextID: 1}
+}
+
+// Copies go in and out so callers can't change stored posts
+func copyPage(p *post.Page) *post.Page {
+	c := *p
+	c.Tags = slices.Clone(p.Tags)
+	return &c
+}
+
+func (s *MemPosts) Get(ctx context.Context, id int) (*post.Page, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.posts[id]
+	if !ok || !p.Deleted.IsZero() {
+		return nil, sql.ErrNoRows
+	}
+
+	return copyPage(p), nil
+}
+
+// Posts matching `keep` without the body, sorted by ID like the database returns them
+func (s *MemPosts) list(keep func(p *post.Page) bool) *[]*post.Page {
+	pages := []*post.Page{}
+	for _, p := range s.posts {
+		if !keep(p) {
+			continue
+		}
+		c := copyPage(p)
+		c.Body = ""
+		pages = append(pages, c)
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].ID < pages[j].ID
+	})
+
+	return &pages
+}
+
+func (s *MemPosts) All(ctx context.Context) (*[]*post.Page, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(func(p *post.Page) bool { return p.Deleted.IsZero() }), nil
+}
+
+func (s *MemPosts) TitleExists(ctx context.Context, title string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.posts {
+		if p.Deleted.IsZero() && p.Title == title {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *MemPosts) Insert(ctx context.Context, p *post.Page) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p.ID = s.nextID
+	s.nextID++
+	p.Created = time.Unix(time.Now().Unix(), 0)
+	p.Updated = p.Created
+	p.Version = 1
+	s.posts[p.ID] = copyPage(p)
+
+	return nil
+}
+
+func (s *MemPosts) Save(ctx context.Context, p *post.Page) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.posts[p.ID]
+	if !ok || !stored.Deleted.IsZero() {
+		return sql.ErrNoRows
+	}
+	if stored.Version != p.Version {
+		return post.ErrConflict
+	}
+
+	p.Created = stored.Created
+	p.Updated = time.Unix(time.Now().Unix(), 0)
+	p.Version++
+	s.posts[p.ID] = copyPage(p)
+
+	return nil
+}
+
+func (s *MemPosts) Trash(ctx context.Context, id int, user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.posts[id]
+	if !ok || !p.Deleted.IsZero() {
+		return sql.ErrNoRows
+	}
+	p.Deleted = time.Unix(time.Now().Unix(), 0)
+	p.DeletedBy = user
+
+	return nil
+}
+
+func (s *MemPosts) GetTrash(ctx context.Context) (*[]*post.Page, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pages := s.list(func(p *post.Page) bool { return !p.Deleted.IsZero() })
+	sort.SliceStable(*pages, func(i, j int) bool {
+		return (*pages)[i].Deleted.After((*pages)[j].Deleted)
+	})
+
+	return pages, nil
+}
+
+func (s *MemPosts) Restore(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.posts[id]
+	if !ok || p.Deleted.IsZero() {
+		return sql.ErrNoRows
+	}
+	p.Deleted = time.Time{}
+	p.DeletedBy = ""
+
+	return nil
+}
+
+func (s *MemPosts) Purge(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.posts[id]
+	if !ok || p.Deleted.IsZero() {
+		return sql.ErrNoRows
+	}
+	delete(s.posts, id)
+
+	return nil
+}
+
+func (s *MemPosts) PurgeOlder(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, p := range s.posts {
+		if !p.Deleted.IsZero() && p.Deleted.Before(before) {
+			delete(s.posts, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+var ErrUserExists error = errors.New("Lietotājs jau eksistē")
+
+// UserStore kept in memory
+type MemUsers struct {
+	mu     sync.Mutex
+	users  map[int]*User
+	nextID int
+}
+
+func NewMemUsers() *MemUsers {
+	return &MemUsers{users: make(map[int]*User), nextID: 1}
+}
+
+func (s *MemUsers) Get(ctx context.Context, id int) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	c := *u
+
+	return &c, nil
+}
+
+func (s *MemUsers) ByName(ctx context.Context, name string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Name == name {
+			c := *u
+			return &c, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (s *MemUsers) Count(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.users), nil
+}
+
+func (s *MemUsers) Create(ctx context.Context, name string, pswdHash []byte) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Name == name {
+			return nil, ErrUserExists
+		}
+	}
+
+	u := User{ID: s.nextID, Name: name, PswdHash: pswdHash}
+	s.nextID++
+	s.users[u.ID] = &u
+	c := u
+
+	return &c, nil
+}
+
+func (s *MemUsers) SetSession(ctx context.Context, id int, sidHash []byte, start time.Time, age time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	u.SID = sidHash
+	u.SStart = start
+	u.SAge = age
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"dtla/internal/database"
+	"dtla/internal/post"
+	"time"
+)
+
+// PostStore on top of the functions in the post package
+type SQLitePosts struct {
+	db *sql.DB
+}
+
+func NewSQLitePosts(db *sql.DB) *SQLitePosts {
+	return &SQLitePosts{db: db}
+}
+
+func (s *SQLitePosts) Get(ctx context.Context, id int) (*post.Page, error) {
+	return post.GetPage(ctx, s.db, id)
+}
+
+func (s *SQLitePosts) All(ctx context.Context) (*[]*post.Page, error) {
+	return post.GetAllPages(ctx, s.db)
+}
+
+func (s *SQLitePosts) TitleExists(ctx context.Context, title string) (bool, error) {
+	return post.TitleExists(ctx, s.db, title)
+}
+
+func (s *SQLitePosts) Insert(ctx context.Context, p *post.Page) error {
+	return p.Insert(ctx, s.db)
+}
+
+func (s *SQLitePosts) Save(ctx context.Context, p *post.Page) error {
+	return p.Save(ctx, s.db)
+}
+
+func (s *SQLitePosts) Trash(ctx context.Context, id int, user string) error {
+	return post.Trash(ctx, s.db, id, user)
+}
+
+func (s *SQLitePosts) GetTrash(ctx context.Context) (*[]*post.Page, error) {
+	return post.GetTrash(ctx, s.db)
+}
+
+func (s *SQLitePosts) Restore(ctx context.Context, id int) error {
+	return post.Restore(ctx, s.db, id)
+}
+
+func (s *SQLitePosts) Purge(ctx context.Context, id int) error {
+	return post.Purge(ctx, s.db, id)
+}
+
+func (s *SQLitePosts) PurgeOlder(ctx context.Context, before time.Time) (int64, error) {
+	return post.PurgeOlder(ctx, s.db, before)
+}
+
+// UserStore for the users table
+type SQLiteUsers struct {
+	db *sql.DB
+}
+
+func NewSQLiteUsers(db *sql.DB) *SQLiteUsers {
+	return &SQLiteUsers{db: db}
+}
+
+func (s *SQLiteUsers) Get(ctx context.Context, id int) (*User, error) {
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	return s.queryUser(ctx, "WHERE id IS ?", id)
+}
+
+func (s *SQLiteUsers) ByName(ctx context.Context, name string) (*User, error) {
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	return s.queryUser(ctx, "WHERE user IS ?", name)
+}
+
+func (s *SQLiteUsers) queryUser(ctx context.Context, where string, args ...any) (*User, error) {
+	var err error
+
+	var u User
+	var pswd string
+	var sstart, sage sql.NullInt64
+	err = s.db.QueryRowContext(ctx, "SELECT id, user, pswd, sid, sstart, sage FROM users "+where, args...).
+		Scan(&u.ID, &u.Name, &pswd, &u.SID, &sstart, &sage)
+	if err != nil {
+		return nil, err
+	}
+	u.PswdHash = []byte(pswd)
+	u.SStart = time.Unix(sstart.Int64, 0)
+	// Stored as seconds
+	u.SAge = time.Duration(sage.Int64) * time.Second
+
+	return &u, nil
+}
+
+func (s *SQLiteUsers) Count(ctx context.Context) (int, error) {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	var count int
+	err = s.db.QueryRowContext(ctx, "SELECT count(*) FROM users").Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (s *SQLiteUsers) Create(ctx context.Context, name string, pswdHash []byte) (*User, error) {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	res, err := s.db.ExecContext(ctx, "INSERT INTO users (user, pswd) VALUES (?, ?)", name, string(pswdHash))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &User{ID: int(id), Name: name, PswdHash: pswdHash}, nil
+}
+
+func (s *SQLiteUsers) SetSession(ctx context.Context, id int, sidHash []byte, start time.Time, age time.Duration) error {
+	var err error
+
+	ctx, done := database.Start(ctx)
+	defer done()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET sid = ?, sstart = ?, sage = ? WHERE id IS ?", string(sidHash), start.Unix(), int(age.Seconds()), id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
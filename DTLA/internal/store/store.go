@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"dtla/internal/post"
+	"time"
+)
+
+// Storage of posts and users used by the server, so it can run on the SQLite
+// database or in memory for tests and demos. Methods return sql.ErrNoRows when
+// what they work on doesn't exist, whatever the implementation, and PostStore.Save()
+// returns post.ErrConflict like Page.Save() does.
+//
+// Every call takes the context of the request it's made for, so it stops
+// when the client goes away.
+//
+// Comments, translations and drafts are only stored in the database.
+
+type PostStore interface {
+	Get(ctx context.Context, id int) (*post.Page, error)
+	// Posts that aren't in the trash, without the body
+	All(ctx context.Context) (*[]*post.Page, error)
+	TitleExists(ctx context.Context, title string) (bool, error)
+
+	// Sets ID, Created, Updated and Version
+	Insert(ctx context.Context, p *post.Page) error
+	// Update the post if it still has the version it was loaded with
+	Save(ctx context.Context, p *post.Page) error
+
+	Trash(ctx context.Context, id int, user string) error
+	// Posts in the trash, most recently deleted first
+	GetTrash(ctx context.Context) (*[]*post.Page, error)
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int) error
+	PurgeOlder(ctx context.Context, before time.Time) (int64, error)
+}
+
+type User struct {
+	ID       int
+	Name     string
+	PswdHash []byte
+
+	// Hash of the session ID, nil if the user never logged in
+	SID    []byte
+	SStart time.Time
+	SAge   time.Duration
+}
+
+type UserStore interface {
+	Get(ctx context.Context, id int) (*User, error)
+	ByName(ctx context.Context, name string) (*User, error)
+	Count(ctx context.Context) (int, error)
+	Create(ctx context.Context, name string, pswdHash []byte) (*User, error)
+	SetSession(ctx context.Context, id int, sidHash []byte, start time.Time, age time.Duration) error
+}
@@ -3,40 +3,41 @@ package util
 import (
 	"database/sql"
 	"net/http"
+	"net/url"
 	"time"
 )
 
 type Auth struct {
 	// From request cookie
-	ID int
+	ID int `json:"id"`
 
 	// From database
-	User string
+	User string `json:"user"`
 
 	// From database
-	SID []byte
+	SID []byte `json:"-"`
 
 	// Session creation time
 	// Stored as seconds since UNIX epoch in database
-	SStart time.Time
+	SStart time.Time `json:"-"`
 
 	// Session maximum age until it expires
 	// Stored as seconds in database
-	SAge time.Duration
+	SAge time.Duration `json:"-"`
 
 	// ASDefault - the sid cookie wasn't found
 	// ASError - a different error occured
 	// ASOk when the sid cookie hash comparison succeeds with the one in the database
-	Status uint
+	Status uint `json:"status"`
 
 	// Repeated here so the identifiers can be used in templates
 	// Initialized in makeHandler()
-	ASDefault int
-	ASError   int
-	ASOk      int
+	ASDefault int `json:"-"`
+	ASError   int `json:"-"`
+	ASOk      int `json:"-"`
 
 	// Should be set when Status == ASError
-	Error string
+	Error string `json:"error,omitempty"`
 }
 
 const (
@@ -56,3 +57,23 @@ func ValidCookie(r *http.Request, db *sql.DB) (*Auth, bool, error) {
 	_ = cookie
 	return nil, false, nil
 }
+
+// Whether the request came from a page of this site, by Sec-Fetch-Site or,
+// for browsers that don't send it, by Origin. Requests with neither, like
+// from curl, aren't made by a browser on behalf of a visitor.
+func SameOrigin(r *http.Request) bool {
+	site := r.Header.Get("Sec-Fetch-Site")
+	if site != "" {
+		return site == "same-origin" || site == "none"
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host
+}
@@ -0,0 +1,468 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Responses are compressed with brotli or gzip, whichever the client accepts,
+// brotli first. Static files can have compressed copies next to them named
+// file.br and file.gz, made by Precompress(), which ServeFile() sends instead
+// of compressing the same file again on every request.
+
+// In order of preference, with the suffix of their precompressed copies
+var encodings = []encoding{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+type encoding struct {
+	name string
+	ext  string
+}
+
+// On the fly compression has to keep up with requests, precompressed copies
+// are made once and use the best compression
+const brotliLevel = 5
+
+// Whether content of this type gets smaller when compressed, images other
+// than SVG, video and woff2 fonts already are compressed
+func Compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml",
+		"application/wasm", "font/ttf", "font/otf", "image/vnd.microsoft.icon":
+		return true
+	}
+
+	return false
+}
+
+// The encodings in Accept-Encoding, in our order of preference.
+// Ones with q=0 are refused, * accepts all that aren't named.
+func acceptedEncodings(r *http.Request) []encoding {
+	qs := make(map[string]float64)
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		params = strings.TrimSpace(params)
+		if strings.HasPrefix(params, "q=") {
+			var err error
+			q, err = strconv.ParseFloat(params[len("q="):], 64)
+			if err != nil {
+				continue
+			}
+		}
+		qs[name] = q
+	}
+
+	var accepted []encoding
+	for _, enc := range encodings {
+		q, ok := qs[enc.name]
+		if !ok {
+			q, ok = qs["*"]
+		}
+		if ok && q > 0 {
+			accepted = append(accepted, enc)
+		}
+	}
+
+	return accepted
+}
+
+// Content type of `name` in fsys by its extension or, like http.ServeContent
+// does, by sniffing its first bytes
+func contentType(fsys fs.FS, name string) (string, error) {
+	var err error
+
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype != "" {
+		return ctype, nil
+	}
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var buf [512]byte
+	n, err := io.ReadFull(file, buf[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// Serve the precompressed copy of `name` if the client accepts its encoding
+// and it isn't older than the file. Returns false if there's none to serve.
+func servePrecompressed(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, fileInfo fs.FileInfo) bool {
+	// Only files of these types get copies, the plain file may be served
+	// because of Accept-Encoding too
+	ctype, err := contentType(fsys, name)
+	if err != nil || !Compressible(ctype) {
+		return false
+	}
+	addVary(w.Header(), "Accept-Encoding")
+
+	for _, enc := range acceptedEncodings(r) {
+		if serveEncoded(w, r, fsys, name, fileInfo, enc) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func serveEncoded(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, fileInfo fs.FileInfo, enc encoding) bool {
+	file, err := fsys.Open(name + enc.ext)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	encInfo, err := file.Stat()
+	if err != nil || !encInfo.Mode().IsRegular() || encInfo.ModTime().Before(fileInfo.ModTime()) {
+		return false
+	}
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+
+	// Of the file, not of the compressed copy
+	ctype, err := contentType(fsys, name)
+	if err != nil {
+		return false
+	}
+
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Content-Encoding", enc.name)
+	http.ServeContent(w, r, fileInfo.Name(), encInfo.ModTime(), content)
+	return true
+}
+
+// Add `name` to the Vary header unless it's there already
+func addVary(header http.Header, name string) {
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
+
+// Compress responses of compressible types that are at least minSize bytes
+// long, the rest are sent as they are. minSize <= 0 turns it off.
+func CompressHandler(next http.Handler, minSize int) http.Handler {
+	if minSize <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted := acceptedEncodings(r)
+		// Websockets take over the connection and ranges are of the uncompressed content
+		if len(accepted) == 0 || r.Header.Get("Upgrade") != "" || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := compressWriter{ResponseWriter: w, encoding: accepted[0], minSize: minSize}
+		defer cw.Close()
+		next.ServeHTTP(&cw, r)
+	})
+}
+
+// Buffers the start of the response until it's clear whether it's long
+// enough to compress, headers are only written then
+type compressWriter struct {
+	http.ResponseWriter
+	encoding encoding
+	minSize  int
+
+	status int
+	buf    []byte
+	// Set once decided, nil if the response is sent as it is
+	enc     io.WriteCloser
+	decided bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	// Informational responses don't end the headers
+	if status >= 100 && status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+
+	// Nothing to compress
+	if status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent {
+		// The client may have the compressed response cached with the weak ETag
+		if status == http.StatusNotModified {
+			weakETag(cw.Header())
+		}
+		cw.decide(false)
+	}
+}
+
+// A strong ETag is for one exact representation, the compressed response and
+// the one it was compressed from only share a weak one. If-None-Match uses
+// weak comparison, so either still gets a 304.
+func weakETag(header http.Header) {
+	etag := header.Get("ETag")
+	if etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+}
+
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.decided {
+		cw.buf = append(cw.buf, data...)
+		if len(cw.buf) < cw.minSize {
+			return len(data), nil
+		}
+
+		err := cw.decide(true)
+		if err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if cw.enc != nil {
+		return cw.enc.Write(data)
+	}
+	return cw.ResponseWriter.Write(data)
+}
+
+// Write the headers and what's buffered, compressed if `long` enough and
+// the response is of a compressible type that isn't encoded already
+func (cw *compressWriter) decide(long bool) error {
+	var err error
+
+	cw.decided = true
+	header := cw.Header()
+
+	ctype := header.Get("Content-Type")
+	if ctype == "" && len(cw.buf) > 0 {
+		ctype = http.DetectContentType(cw.buf)
+		header.Set("Content-Type", ctype)
+	}
+
+	// Whether it's compressed depends on Accept-Encoding for every long
+	// enough response of this type, also the ones that aren't
+	if Compressible(ctype) {
+		addVary(header, "Accept-Encoding")
+	}
+	if long && header.Get("Content-Encoding") == "" && Compressible(ctype) {
+		header.Set("Content-Encoding", cw.encoding.name)
+		header.Del("Content-Length")
+		weakETag(header)
+		switch cw.encoding.name {
+		case "br":
+			cw.enc = brotli.NewWriterLevel(cw.ResponseWriter, brotliLevel)
+		default:
+			cw.enc = gzip.NewWriter(cw.ResponseWriter)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+// Send what's left, short responses are only sent now
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			// Handler wrote nothing, let net/http reply as it would
+			return nil
+		}
+		err := cw.decide(false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cw.enc != nil {
+		return cw.enc.Close()
+	}
+	return nil
+}
+
+// Flushing decides even if less than minSize has been written, so
+// streamed responses aren't held back
+func (cw *compressWriter) Flush() {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.decide(len(cw.buf) >= cw.minSize)
+	}
+
+	switch enc := cw.enc.(type) {
+	case *gzip.Writer:
+		enc.Flush()
+	case *brotli.Writer:
+		enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+// For http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Write a gzip and a brotli compressed copy next to every file in `dir` of a
+// compressible type that is at least minSize bytes long, unless there's one
+// already that isn't older than the file. HTML pages are templates and are
+// skipped. Returns how many files were compressed.
+func Precompress(dir string, minSize int64) (int, error) {
+	var err error
+
+	fsys := os.DirFS(dir)
+	count := 0
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) == ".html" {
+			return nil
+		}
+		for _, enc := range encodings {
+			if path.Ext(name) == enc.ext {
+				return nil
+			}
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || info.Size() < minSize {
+			return nil
+		}
+
+		ctype, err := contentType(fsys, name)
+		if err != nil {
+			return err
+		}
+		if !Compressible(ctype) {
+			return nil
+		}
+
+		compressed, err := precompressFile(filepath.Join(dir, filepath.FromSlash(name)), info)
+		if err != nil {
+			return err
+		}
+		if compressed {
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// Returns false if all copies of `filename` were up to date
+func precompressFile(filename string, info fs.FileInfo) (bool, error) {
+	var err error
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+
+	compressed := false
+	for _, enc := range encodings {
+		encInfo, err := os.Stat(filename + enc.ext)
+		if err == nil && !encInfo.ModTime().Before(info.ModTime()) {
+			continue
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch enc.name {
+		case "br":
+			w = brotli.NewWriterLevel(&buf, brotli.BestCompression)
+		default:
+			w, err = gzip.NewWriterLevel(&buf, gzip.BestCompression)
+			if err != nil {
+				return false, err
+			}
+		}
+		_, err = w.Write(data)
+		if err != nil {
+			return false, err
+		}
+		err = w.Close()
+		if err != nil {
+			return false, err
+		}
+
+		err = os.WriteFile(filename+enc.ext, buf.Bytes(), 0644)
+		if err != nil {
+			return false, err
+		}
+		compressed = true
+	}
+
+	return compressed, nil
+}
@@ -1,6 +1,20 @@
 package util
 
-import "errors"
+import (
+	"dtla/internal/apperr"
+	"dtla/internal/i18n"
+	"fmt"
+)
 
 // Returned if session start (seconds since UNIX epoch) + session age (seconds) > time.Now()
-var ErrSessionExpired error = errors.New("Sessija ir beigusies")
+var ErrSessionExpired error = apperr.NewT(apperr.Forbidden, "err.auth.expired")
+
+// What to tell visitors about err in lang, the generic message of its
+// kind if apperr.Message() has none
+func ErrorMessage(err error, lang string) string {
+	msg := apperr.Message(err, lang)
+	if msg == "" {
+		msg = i18n.T(lang, fmt.Sprintf("error.%d.msg", apperr.KindOf(err).Status()))
+	}
+	return msg
+}
@@ -1,15 +1,22 @@
 package util
 
 import (
+	"dtla/internal/apperr"
+	"errors"
+	"io"
+	"io/fs"
 	"log"
 	"net/http"
-	"os"
+	"path/filepath"
 )
 
-func ServeFile(w http.ResponseWriter, r *http.Request, filename string) error {
+// Serve `filename` from fsys, the name may use the OS path separator.
+// Its .br or .gz copy is served instead if the client accepts it.
+func ServeFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, filename string) error {
 	var err error
 
-	file, err := os.Open(filename)
+	name := filepath.ToSlash(filename)
+	file, err := fsys.Open(name)
 	if err != nil {
 		return err
 	}
@@ -21,6 +28,15 @@ func ServeFile(w http.ResponseWriter, r *http.Request, filename string) error {
 		return err
 	}
 
-	http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), file)
+	content, ok := file.(io.ReadSeeker)
+	if !ok || fileInfo.IsDir() {
+		return apperr.Wrap(apperr.NotFound, &fs.PathError{Op: "serve", Path: filename, Err: errors.New("not a regular file")})
+	}
+
+	if servePrecompressed(w, r, fsys, name, fileInfo) {
+		return nil
+	}
+
+	http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), content)
 	return nil
 }
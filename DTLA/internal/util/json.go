@@ -0,0 +1,71 @@
+package util
+
+import (
+	"dtla/internal/apperr"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const jsonContentType = "application/json; charset=utf-8"
+
+type jsonError struct {
+	Error string `json:"error"`
+}
+
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	var err error
+
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(v)
+	if err != nil {
+		errLogger.Output(2, err.Error())
+	}
+}
+
+// Like ExecuteTemplateError() only what apperr.Message() allows is sent, in
+// lang, and the details are only logged
+func WriteJSONError(w http.ResponseWriter, lang string, status int, err error) {
+	errLogger.Output(2, err.Error())
+	msg := apperr.Message(err, lang)
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	WriteJSON(w, status, jsonError{Error: msg})
+}
+
+// Whether the Accept header prefers application/json to text/html, with the
+// one listed first winning a tie. Browsers list text/html so they get pages.
+func WantsJSON(r *http.Request) bool {
+	jsonQ, htmlQ := 0.0, 0.0
+	jsonFirst := false
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+		q := 1.0
+		params = strings.TrimSpace(params)
+		if strings.HasPrefix(params, "q=") {
+			var err error
+			q, err = strconv.ParseFloat(params[len("q="):], 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case "application/json":
+			if jsonQ == 0 && htmlQ == 0 {
+				jsonFirst = true
+			}
+			jsonQ = max(jsonQ, q)
+		case "text/html":
+			htmlQ = max(htmlQ, q)
+		}
+	}
+
+	return jsonQ > htmlQ || (jsonQ > 0 && jsonQ == htmlQ && jsonFirst)
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"dtla/internal/apperr"
 	"fmt"
 	"log"
 	"net/http"
@@ -22,9 +23,12 @@ func LogErrorDepth(depth int, err string) {
 	errLogger.Output(depth, err)
 }
 
+// For when error.html can't be shown, e.g. if rendering a page failed.
+// The details of err are only logged.
 func LogHTTPError(w http.ResponseWriter, err error) {
 	errLogger.Output(2, err.Error())
-	http.Error(w, err.Error(), http.StatusInternalServerError)
+	status := apperr.KindOf(err).Status()
+	http.Error(w, http.StatusText(status), status)
 }
 
 func LogFatal(err string) {
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"dtla/internal/apperr"
 	"errors"
 	"path/filepath"
 	"regexp"
@@ -10,7 +11,7 @@ import (
 func ValidPath(path string) error {
 	re := regexp.MustCompile(`\.\.`)
 	if re.MatchString(path) {
-		return errors.New("can't have '..' in URL path")
+		return apperr.Wrap(apperr.BadRequest, errors.New("can't have '..' in URL path"))
 	}
 	return nil
 }
@@ -26,10 +27,25 @@ func CleanPath(path string) (string, error) {
 	if path != "/" {
 		cleanPath, found := strings.CutPrefix(path, "/")
 		if !found {
-			return "", errors.New("expected URL path to start with '/'")
+			return "", apperr.Wrap(apperr.BadRequest, errors.New("expected URL path to start with '/'"))
 		}
 		return filepath.Clean(cleanPath), nil
 	}
 
 	return "index.html", nil
 }
+
+var slugReplacer *strings.Replacer = strings.NewReplacer(
+	"ā", "a", "č", "c", "ē", "e", "ģ", "g", "ī", "i", "ķ", "k",
+	"ļ", "l", "ņ", "n", "š", "s", "ū", "u", "ž", "z",
+)
+
+var slugInvalid *regexp.Regexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Lowercase ASCII words joined with '-', Latvian letters lose their diacritics.
+// Used for exported file names and heading ids.
+func Slugify(s string) string {
+	s = slugReplacer.Replace(strings.ToLower(s))
+	s = slugInvalid.ReplaceAllString(s, "-")
+	return strings.Trim(s, "-")
+}
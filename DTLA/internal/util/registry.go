@@ -0,0 +1,252 @@
+package util
+
+import (
+	"context"
+	"dtla/internal/apperr"
+	"fmt"
+	htmlT "html/template"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	textT "text/template"
+	"time"
+)
+
+// Every page in the public directory parsed together with the shared
+// *.tmpl.html templates, once at startup instead of on every request.
+// Pages are parsed with html/template, except the ones named in `text`,
+// which escape what they show themselves and are parsed with text/template.
+type Templates struct {
+	pages    fs.FS
+	partials fs.FS
+	text     map[string]bool
+	funcs    map[string]any
+
+	mu       sync.RWMutex
+	html     map[string]*htmlT.Template
+	textTmpl map[string]*textT.Template
+	// Of all parsed files, to notice changes in Watch()
+	modTimes map[string]time.Time
+}
+
+const partialsPattern = "*.tmpl.html"
+
+// Parse the *.html pages in `pages`, skipping the shared templates if they're
+// in the same directory, and the *.tmpl.html templates in `partials`.
+// `funcs` can be called from all of them.
+func NewTemplates(pages fs.FS, partials fs.FS, text []string, funcs map[string]any) (*Templates, error) {
+	var err error
+
+	t := Templates{pages: pages, partials: partials, text: make(map[string]bool), funcs: funcs}
+	for _, name := range text {
+		t.text[name] = true
+	}
+
+	err = t.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// Parse everything again, the templates in use are only replaced if all of it succeeds
+func (t *Templates) Load() error {
+	var err error
+
+	modTimes, err := t.scan()
+	if err != nil {
+		return err
+	}
+
+	htmlBase, err := htmlT.New("").Funcs(t.funcs).ParseFS(t.partials, partialsPattern)
+	if err != nil {
+		return err
+	}
+	textBase, err := textT.New("").Funcs(t.funcs).ParseFS(t.partials, partialsPattern)
+	if err != nil {
+		return err
+	}
+
+	html := make(map[string]*htmlT.Template)
+	text := make(map[string]*textT.Template)
+	for name := range modTimes {
+		if !strings.HasPrefix(name, "page:") {
+			continue
+		}
+		name = strings.TrimPrefix(name, "page:")
+
+		data, err := fs.ReadFile(t.pages, name)
+		if err != nil {
+			return err
+		}
+
+		if t.text[name] {
+			tmpl, err := textBase.Clone()
+			if err != nil {
+				return err
+			}
+			text[name], err = tmpl.New(name).Parse(string(data))
+			if err != nil {
+				return err
+			}
+		} else {
+			tmpl, err := htmlBase.Clone()
+			if err != nil {
+				return err
+			}
+			html[name], err = tmpl.New(name).Parse(string(data))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	t.mu.Lock()
+	t.html = html
+	t.textTmpl = text
+	t.modTimes = modTimes
+	t.mu.Unlock()
+
+	return nil
+}
+
+// Modification times of the pages and shared templates, keyed by "page:" or "partial:" and the path
+func (t *Templates) scan() (map[string]time.Time, error) {
+	var err error
+
+	modTimes := make(map[string]time.Time)
+
+	err = fs.WalkDir(t.pages, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != ".html" || strings.HasSuffix(name, ".tmpl.html") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		modTimes["page:"+name] = info.ModTime()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	partials, err := fs.Glob(t.partials, partialsPattern)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range partials {
+		info, err := fs.Stat(t.partials, name)
+		if err != nil {
+			return nil, err
+		}
+		modTimes["partial:"+name] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+// Check for changed, added or removed files every `interval` and reload if
+// there are any, until ctx is done. Errors are logged and the templates that
+// were loaded before are kept, so a typo while editing doesn't break every page.
+func (t *Templates) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		modTimes, err := t.scan()
+		if err != nil {
+			LogError(err.Error())
+			continue
+		}
+
+		t.mu.RLock()
+		changed := !sameModTimes(modTimes, t.modTimes)
+		t.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		err = t.Load()
+		if err != nil {
+			LogErrorf("Veidnes netika pārlādētas: %s", err.Error())
+			// Not tried again until something changes
+			t.mu.Lock()
+			t.modTimes = modTimes
+			t.mu.Unlock()
+			continue
+		}
+		LogInfo("Veidnes pārlādētas")
+	}
+}
+
+func sameModTimes(a map[string]time.Time, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, modTime := range a {
+		if !b[name].Equal(modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// Names of all parsed pages, sorted
+func (t *Templates) Names() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var names []string
+	for name := range t.html {
+		names = append(names, name)
+	}
+	for name := range t.textTmpl {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (t *Templates) lookupHTML(filename string) (*htmlT.Template, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	tmpl, ok := t.html[pageName(filename)]
+	if !ok {
+		return nil, apperr.Wrap(apperr.NotFound, fmt.Errorf("Lapa '%s' neeksistē", filename))
+	}
+
+	return tmpl, nil
+}
+
+func (t *Templates) lookupText(filename string) (*textT.Template, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	tmpl, ok := t.textTmpl[pageName(filename)]
+	if !ok {
+		return nil, apperr.Wrap(apperr.NotFound, fmt.Errorf("Lapa '%s' neeksistē", filename))
+	}
+
+	return tmpl, nil
+}
+
+// Pages are named by their slash separated path in the public directory
+func pageName(filename string) string {
+	return path.Clean(filepath.ToSlash(filename))
+}
@@ -1,36 +1,47 @@
 package util
 
 import (
-	htmlT "html/template"
+	"dtla/internal/apperr"
+	"encoding/json"
+	"io"
 	"net/http"
-	"path/filepath"
-	textT "text/template"
 )
 
 type TmplData struct {
-	URLPath string
-	Auth    Auth
-	Data    any
-	ErrMsg  string
+	URLPath string `json:"urlPath"`
+	Auth    Auth   `json:"auth"`
+	Data    any    `json:"data"`
+	ErrMsg  string `json:"errMsg,omitempty"`
+	// HTTP status of the error page, picks its heading
+	ErrStatus int `json:"errStatus,omitempty"`
+
+	// Set when rendering the static site, hides things that need the server
+	Static bool `json:"-"`
+
+	// Comments waiting for moderation, only counted for logged in users
+	PendingComments int `json:"pendingComments"`
+
+	// Language the page is read in, one of post.Languages
+	Lang string `json:"lang"`
 }
 
-func ExecuteTemplate(w http.ResponseWriter, r *http.Request, filename string, tmplDir string, data any) error {
+// `w` is an io.Writer so pages can also be rendered to files, `r` may be nil then.
+// Requests that ask for JSON get `data` as JSON instead of the page.
+func ExecuteTemplate(w io.Writer, r *http.Request, filename string, tmpls *Templates, data any) error {
 	var err error
 
-	tmpl, err := htmlT.ParseGlob(tmplDir + "/*.tmpl.html")
-	if err != nil {
-		LogError(err.Error())
+	ok, err := executeJSON(w, r, data)
+	if ok {
 		return err
 	}
 
-	_, err = tmpl.ParseFiles(filename)
+	tmpl, err := tmpls.lookupHTML(filename)
 	if err != nil {
 		LogError(err.Error())
 		return err
 	}
 
-	filename = filepath.Base(filename)
-	err = tmpl.ExecuteTemplate(w, filename, data)
+	err = tmpl.Execute(w, data)
 	if err != nil {
 		LogError(err.Error())
 		return err
@@ -39,20 +50,20 @@ func ExecuteTemplate(w http.ResponseWriter, r *http.Request, filename string, tm
 	return nil
 }
 
-func ExecuteTemplateHTML(w http.ResponseWriter, r *http.Request, filename string, tmplDir string, data any) error {
+func ExecuteTemplateHTML(w io.Writer, r *http.Request, filename string, tmpls *Templates, data any) error {
 	var err error
 
-	tmpl, err := textT.ParseGlob(tmplDir + "/*.tmpl.html")
-	if err != nil {
+	ok, err := executeJSON(w, r, data)
+	if ok {
 		return err
 	}
 
-	_, err = tmpl.ParseFiles(filename)
+	tmpl, err := tmpls.lookupText(filename)
 	if err != nil {
 		return err
 	}
 
-	err = tmpl.ExecuteTemplate(w, filename, data)
+	err = tmpl.Execute(w, data)
 	if err != nil {
 		return err
 	}
@@ -60,26 +71,68 @@ func ExecuteTemplateHTML(w http.ResponseWriter, r *http.Request, filename string
 	return nil
 }
 
-func ExecuteTemplateError(w http.ResponseWriter, r *http.Request, tmplDir string, tmplData *TmplData, msg string) {
+// Reply with error.html and the status code of appErr's kind. Only messages
+// meant for visitors are shown, the rest of appErr goes to the log.
+func ExecuteTemplateError(w http.ResponseWriter, r *http.Request, tmpls *Templates, tmplData *TmplData, appErr error) {
 	var err error
 
-	tmplData.ErrMsg = msg
-	LogError(tmplData.ErrMsg)
-	err = ExecuteTemplate(w, r, "error.html", tmplDir, tmplData)
+	tmplData.ErrStatus = apperr.KindOf(appErr).Status()
+	tmplData.ErrMsg = apperr.Message(appErr, tmplData.Lang)
+	errLogger.Output(2, appErr.Error())
+
+	WriteTemplateHeader(w, r, tmplData.ErrStatus)
+	err = ExecuteTemplate(w, r, "error.html", tmpls, tmplData)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		LogError(err.Error())
 	}
 }
 
-func ExecuteTemplateLoginWithError(w http.ResponseWriter, r *http.Request, tmplData *TmplData, tmplDir string, msg error) {
+// The login form again with the error, shown like by ExecuteTemplateError()
+func ExecuteTemplateLoginWithError(w http.ResponseWriter, r *http.Request, tmplData *TmplData, tmpls *Templates, loginErr error) {
 	var err error
 
+	status := apperr.KindOf(loginErr).Status()
 	tmplData.Auth.Status = ASError
-	tmplData.Auth.Error = msg.Error()
+	tmplData.Auth.Error = ErrorMessage(loginErr, tmplData.Lang)
+	errLogger.Output(2, loginErr.Error())
 
-	LogError(msg.Error())
-	err = ExecuteTemplate(w, r, "login.html", tmplDir, tmplData)
+	WriteTemplateHeader(w, r, status)
+	err = ExecuteTemplate(w, r, "login.html", tmpls, tmplData)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		LogError(err.Error())
+	}
+}
+
+// Answer with data as JSON if w is the response to a request that asks for it,
+// returns false if it doesn't and the page should be rendered
+func executeJSON(w io.Writer, r *http.Request, data any) (bool, error) {
+	var err error
+
+	rw, ok := w.(http.ResponseWriter)
+	if !ok || r == nil {
+		return false, nil
+	}
+
+	addVary(rw.Header(), "Accept")
+	if !WantsJSON(r) {
+		return false, nil
+	}
+
+	rw.Header().Set("Content-Type", jsonContentType)
+	err = json.NewEncoder(rw).Encode(data)
+	if err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// Use instead of w.WriteHeader() before executing a template, the headers
+// that depend on whether the page is answered as JSON have to be set first
+func WriteTemplateHeader(w http.ResponseWriter, r *http.Request, status int) {
+	addVary(w.Header(), "Accept")
+	if WantsJSON(r) {
+		w.Header().Set("Content-Type", jsonContentType)
 	}
+	w.WriteHeader(status)
 }
@@ -0,0 +1,130 @@
+"use strict";
+
+/** @type {HTMLTextAreaElement} */
+let editBody;
+
+window.addEventListener("load", () => {
+	editBody =
+		/** @type {HTMLTextAreaElement} */
+		(document.getElementById("input-post-body"));
+	if (!editBody)
+		throw Error();
+
+	for (const button of document.getElementsByClassName("media-insert")) {
+		button.addEventListener("click", () => {
+			const url = /** @type {HTMLButtonElement} */ (button).dataset.url;
+			if (/** @type {HTMLButtonElement} */ (button).dataset.image === "true")
+				insertAtCursor(`<img src="${url}"/>`);
+			else
+				insertAtCursor(`<video src="${url}" controls></video>`);
+		});
+	}
+
+	const form = /** @type {HTMLFormElement | null} */ (document.querySelector("form[data-draft]"));
+	if (form) {
+		startAutosave(form);
+		startHeartbeat(form);
+	}
+});
+
+/** Should be well below the lock expiry time on the server */
+const heartbeatInterval = 30 * 1000;
+
+/**
+ * Keep the edit lock of the post alive and warn when someone takes it over
+ * @param {HTMLFormElement} form
+ */
+function startHeartbeat(form) {
+	const status = /** @type {HTMLElement} */ (document.getElementById("lock-status"));
+	let submitting = false;
+
+	setInterval(() => {
+		fetch(/** @type {string} */ (form.dataset.lock), { method: "POST" }).then(async (res) => {
+			if (res.status !== 409)
+				return;
+			const json = await res.json();
+			status.textContent = `${json.error}. ${form.dataset.msgLocked}`;
+			status.hidden = false;
+		}).catch(() => { });
+	}, heartbeatInterval);
+
+	form.addEventListener("submit", (event) => {
+		// The preview button submits the form too, but the page stays open
+		if (!event.submitter?.hasAttribute("formaction"))
+			submitting = true;
+	});
+	window.addEventListener("pagehide", () => {
+		// Saving releases the lock on the server
+		if (!submitting)
+			navigator.sendBeacon(/** @type {string} */ (form.dataset.release));
+	});
+}
+
+/** How often the edit form is saved as a draft if it changed */
+const autosaveInterval = 30 * 1000;
+
+/**
+ * Periodically save the form to the URL in its data-draft attribute,
+ * the status messages are in its data-msg-* attributes
+ * @param {HTMLFormElement} form
+ */
+function startAutosave(form) {
+	const status = /** @type {HTMLElement} */ (document.getElementById("draft-status"));
+	let saved = new URLSearchParams(/** @type {any} */ (new FormData(form))).toString();
+	let submitting = false;
+
+	/** @param {boolean} keepalive */
+	const save = (keepalive) => {
+		const data = new URLSearchParams(/** @type {any} */ (new FormData(form))).toString();
+		if (submitting || data === saved)
+			return;
+
+		fetch(/** @type {string} */ (form.dataset.draft), {
+			method: "POST",
+			headers: {
+				"Content-Type": "application/x-www-form-urlencoded",
+				"Accept": "application/json",
+			},
+			body: data,
+			keepalive: keepalive,
+		}).then(async (res) => {
+			// 403 from servers that answer an expired session with the error page
+			if (res.status === 401 || res.status === 403) {
+				status.textContent = /** @type {string} */ (form.dataset.msgExpired);
+				return;
+			}
+			const json = await res.json();
+			if (!res.ok) {
+				status.textContent = `${form.dataset.msgFailed}: ${json.error}`;
+				return;
+			}
+			saved = data;
+			status.textContent = `${form.dataset.msgSaved} ${new Date(json.saved).toLocaleTimeString()}`;
+		}).catch(() => {
+			status.textContent = /** @type {string} */ (form.dataset.msgFailed);
+		});
+	};
+
+	setInterval(() => save(false), autosaveInterval);
+	document.addEventListener("visibilitychange", () => {
+		if (document.visibilityState === "hidden")
+			save(true);
+	});
+	form.addEventListener("submit", (event) => {
+		// The preview button submits the form too, but the page stays open
+		if (!event.submitter?.hasAttribute("formaction"))
+			submitting = true;
+	});
+}
+
+/**
+ * Insert text into the post body where the cursor is or replace the selection
+ * @param {string} text
+ */
+function insertAtCursor(text) {
+	const start = editBody.selectionStart;
+	const end = editBody.selectionEnd;
+	editBody.value = editBody.value.slice(0, start) + text + editBody.value.slice(end);
+	editBody.selectionStart = editBody.selectionEnd = start + text.length;
+	editBody.focus();
+}
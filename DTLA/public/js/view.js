@@ -0,0 +1,20 @@
+"use strict";
+
+window.addEventListener("load", () => {
+	for (const anchor of document.getElementsByClassName("heading-anchor")) {
+		anchor.addEventListener("click", (event) => {
+			const link = /** @type {HTMLAnchorElement} */ (anchor);
+			// Without the clipboard, e.g. over plain HTTP, the link still goes to the heading
+			if (!navigator.clipboard)
+				return;
+
+			event.preventDefault();
+			history.replaceState(null, "", link.hash);
+			link.scrollIntoView();
+			navigator.clipboard.writeText(link.href).then(() => {
+				link.textContent = "Nokopēts";
+				setTimeout(() => link.textContent = "#", 1500);
+			});
+		});
+	}
+});